@@ -1,10 +1,14 @@
 package container
 
 import (
+	"encoding/hex"
+	"strings"
+
 	"github.com/jfrog/jfrog-cli-core/v2/artifactory/utils"
 	"github.com/jfrog/jfrog-cli-core/v2/artifactory/utils/container"
 	"github.com/jfrog/jfrog-cli-core/v2/common/build"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
 )
 
 type BuildDockerCreateCommand struct {
@@ -25,7 +29,19 @@ func (bdc *BuildDockerCreateCommand) SetImageNameWithDigest(filePath string) (er
 	return
 }
 
+// Set the tag and the manifest sha256 of an image in Artifactory directly, for images built by tools which don't generate
+// an image-name-with-digest file. The sha256 is expected in the 'sha256:<digest>' format.
+// For multi-platform images, the layers of all the platforms in the image's fat-manifest are collected.
+func (bdc *BuildDockerCreateCommand) SetImageTagWithDigest(imageTag, manifestSha256 string) *BuildDockerCreateCommand {
+	bdc.SetImageTag(imageTag)
+	bdc.manifestSha256 = manifestSha256
+	return bdc
+}
+
 func (bdc *BuildDockerCreateCommand) Run() error {
+	if err := bdc.validateImage(); err != nil {
+		return err
+	}
 	if err := bdc.init(); err != nil {
 		return err
 	}
@@ -64,6 +80,21 @@ func (bdc *BuildDockerCreateCommand) Run() error {
 	return build.SaveBuildInfo(buildName, buildNumber, project, buildInfo)
 }
 
+func (bdc *BuildDockerCreateCommand) validateImage() error {
+	if bdc.image == nil || bdc.image.Name() == "" {
+		return errorutils.CheckErrorf("the image tag is missing")
+	}
+	// Validates that the tag includes the registry and the image name, which are required to search the image in Artifactory.
+	if _, err := bdc.image.GetImageLongNameWithTag(); err != nil {
+		return err
+	}
+	digest, found := strings.CutPrefix(bdc.manifestSha256, "sha256:")
+	if _, err := hex.DecodeString(digest); !found || err != nil || len(digest) != 64 {
+		return errorutils.CheckErrorf("unexpected manifest sha256 '%s' of image '%s'. Expecting the 'sha256:<digest>' format", bdc.manifestSha256, bdc.image.Name())
+	}
+	return nil
+}
+
 func (bdc *BuildDockerCreateCommand) CommandName() string {
 	return "rt_build_docker_create"
 }
@@ -0,0 +1,66 @@
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testManifestSha256 = "sha256:" + strings.Repeat("a1", 32)
+
+func TestSetImageTagWithDigest(t *testing.T) {
+	command := NewBuildDockerCreateCommand().SetImageTagWithDigest("my-registry/docker-local/hello-world:1.0", testManifestSha256)
+	assert.Equal(t, "my-registry/docker-local/hello-world:1.0", command.image.Name())
+	assert.Equal(t, testManifestSha256, command.manifestSha256)
+
+	repoPath, err := command.image.GetImageLongName()
+	assert.NoError(t, err)
+	assert.Equal(t, "docker-local/hello-world", repoPath)
+	tag, err := command.image.GetImageTag()
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", tag)
+
+	// Without a tag, the 'latest' tag is used.
+	command = NewBuildDockerCreateCommand().SetImageTagWithDigest("my-registry/docker-local/hello-world", testManifestSha256)
+	tag, err = command.image.GetImageTag()
+	assert.NoError(t, err)
+	assert.Equal(t, "latest", tag)
+
+	// Without the registry and the image name, the image can't be searched in Artifactory.
+	command = NewBuildDockerCreateCommand().SetImageTagWithDigest("hello-world", testManifestSha256)
+	_, err = command.image.GetImageTag()
+	assert.Error(t, err)
+}
+
+func TestValidateImage(t *testing.T) {
+	testCases := []struct {
+		name           string
+		imageTag       string
+		manifestSha256 string
+		expectedError  string
+	}{
+		{"valid", "my-registry/docker-local/hello-world:1.0", testManifestSha256, ""},
+		{"without tag", "my-registry/docker-local/hello-world", testManifestSha256, ""},
+		{"missing image tag", "", testManifestSha256, "the image tag is missing"},
+		{"missing image name", "hello-world:1.0", testManifestSha256, "is missing '/'"},
+		{"missing sha256", "my-registry/docker-local/hello-world:1.0", "", "unexpected manifest sha256"},
+		{"missing sha256 prefix", "my-registry/docker-local/hello-world:1.0", strings.TrimPrefix(testManifestSha256, "sha256:"), "unexpected manifest sha256"},
+		{"unexpected algorithm", "my-registry/docker-local/hello-world:1.0", "sha1:" + strings.Repeat("a1", 20), "unexpected manifest sha256"},
+		{"short digest", "my-registry/docker-local/hello-world:1.0", "sha256:a1b2", "unexpected manifest sha256"},
+		{"non-hex digest", "my-registry/docker-local/hello-world:1.0", "sha256:" + strings.Repeat("z", 64), "unexpected manifest sha256"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := NewBuildDockerCreateCommand().SetImageTagWithDigest(testCase.imageTag, testCase.manifestSha256).validateImage()
+			if testCase.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, testCase.expectedError)
+			}
+		})
+	}
+
+	// The image is required when it wasn't set.
+	assert.ErrorContains(t, NewBuildDockerCreateCommand().validateImage(), "the image tag is missing")
+}
@@ -1,8 +1,21 @@
 package npm
 
 import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	biUtils "github.com/jfrog/build-info-go/build/utils"
+	commandUtils "github.com/jfrog/jfrog-cli-core/v2/artifactory/commands/utils"
 	"github.com/jfrog/jfrog-cli-core/v2/common/build"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/ioutils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/io/fileutils"
 )
 
 type CommonArgs struct {
@@ -31,3 +44,129 @@ func (ca *CommonArgs) SetRepo(repo string) *CommonArgs {
 	ca.repo = repo
 	return ca
 }
+
+// ComputeNpmModuleId reads the package.json in the given path, and returns the id of the build-info module of the package,
+// as it is set by the npm commands when no custom module is configured.
+// For example: 'npm-example:0.0.3', or 'jfrog:npm-example:0.0.3' for the scoped package '@jfrog/npm-example'.
+// An error is returned if the package.json is missing the package's name or version.
+func ComputeNpmModuleId(packageJsonPath string) (string, error) {
+	packageJson, err := os.ReadFile(packageJsonPath)
+	if err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	packageInfo, err := biUtils.ReadPackageInfo(packageJson, nil)
+	if err != nil {
+		return "", errorutils.CheckErrorf("failed to parse %s: %s", packageJsonPath, err.Error())
+	}
+	if packageInfo.Name == "" || packageInfo.Version == "" {
+		return "", errorutils.CheckErrorf("couldn't compute the build-info module id, because the 'name' or 'version' fields are missing in %s", packageJsonPath)
+	}
+	return packageInfo.BuildInfoModuleId(), nil
+}
+
+// IsNpmrcArtifactoryConfigured reads the .npmrc file in the given project directory, and reports whether its registry is the expected
+// Artifactory npm registry, and it contains the authentication config of this registry (_auth or _authToken, scoped to the registry or not).
+// If the project has no .npmrc file, false is returned with no error.
+func IsNpmrcArtifactoryConfigured(workingDir string, expectedRegistry string) (bool, error) {
+	npmrcPath := filepath.Join(workingDir, npmrcFileName)
+	exists, err := fileutils.IsFileExists(npmrcPath, false)
+	if err != nil || !exists {
+		return false, err
+	}
+	content, err := os.ReadFile(npmrcPath)
+	if err != nil {
+		return false, errorutils.CheckError(err)
+	}
+	expectedRegistry = strings.TrimSuffix(expectedRegistry, "/")
+	// The registry URL without the protocol, as used in the keys of configs scoped to the registry (//host/path/:_authToken).
+	scopedKeyPrefix := "//" + expectedRegistry[strings.Index(expectedRegistry, "://")+len("://"):]
+	var registryConfigured, authConfigured bool
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key == commandUtils.NpmConfigRegistryKey {
+			registryConfigured = strings.TrimSuffix(value, "/") == expectedRegistry
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		// The registry URL in scoped keys may contain a port, so the auth key is after the last colon.
+		registryKey, authKey := "", key
+		scoped := strings.HasPrefix(key, "//")
+		if scoped {
+			separatorIndex := strings.LastIndex(key, ":")
+			if separatorIndex == -1 {
+				continue
+			}
+			registryKey, authKey = key[:separatorIndex], key[separatorIndex+1:]
+		}
+		if authKey != commandUtils.NpmConfigAuthKey && authKey != commandUtils.NpmConfigAuthTokenKey {
+			continue
+		}
+		if !scoped || strings.TrimSuffix(registryKey, "/") == scopedKeyPrefix {
+			authConfigured = true
+		}
+	}
+	return registryConfigured && authConfigured, nil
+}
+
+// WithTempNpmrc replaces the .npmrc file in the given directory with the given content while fn runs, and then restores the original .npmrc,
+// or removes the temporary one if the directory had no .npmrc. The original .npmrc is restored even if fn fails or panics.
+// The temporary .npmrc starts with a comment which marks it as temporary.
+// The .npmrc files left behind by a previous run, which was interrupted before restoring the original .npmrc, are restored or removed first.
+func WithTempNpmrc(workingDir string, content []byte, fn func() error) (err error) {
+	if err = restoreLeftoverNpmrc(workingDir); err != nil {
+		return
+	}
+	npmrcPath := filepath.Join(workingDir, npmrcFileName)
+	restoreNpmrc, err := ioutils.BackupFile(npmrcPath, npmrcBackupFileName)
+	if err != nil {
+		return
+	}
+	defer func() {
+		err = errors.Join(err, restoreNpmrc())
+	}()
+	// The marker allows removing the temporary .npmrc if the run is interrupted before restoring the original .npmrc.
+	if err = writeNpmrcAtomically(npmrcPath, append([]byte(generatedNpmrcMarker+"\n"), content...)); err != nil {
+		return
+	}
+	return fn()
+}
+
+// Returns the content of the package.json file in the given npm package tarball.
+func readPackageJsonFromTarball(packedFilePath string) (packageJson []byte, err error) {
+	tarball, err := os.Open(packedFilePath)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	defer func() {
+		err = errors.Join(err, errorutils.CheckError(tarball.Close()))
+	}()
+	gZipReader, err := gzip.NewReader(tarball)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+
+	tarReader := tar.NewReader(gZipReader)
+	for {
+		hdr, err := tarReader.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil, errorutils.CheckErrorf("Could not find 'package.json' in the compressed npm package: " + packedFilePath)
+			}
+			return nil, errorutils.CheckError(err)
+		}
+		if hdr.Name == "package/package.json" {
+			packageJson, err = io.ReadAll(tarReader)
+			return packageJson, errorutils.CheckError(err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package npm
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeNpmModuleId(t *testing.T) {
+	testCases := []struct {
+		name             string
+		packageJson      string
+		expectedModuleId string
+		expectedError    bool
+	}{
+		{name: "simple", packageJson: `{"name":"npm-example","version":"0.0.3"}`, expectedModuleId: "npm-example:0.0.3"},
+		{name: "scoped", packageJson: `{"name":"@jfrog/npm-example","version":"1.2.3"}`, expectedModuleId: "jfrog:npm-example:1.2.3"},
+		{name: "with dependencies", packageJson: `{"name":"npm-example","version":"0.0.3","dependencies":{"send":"^0.16.2"}}`, expectedModuleId: "npm-example:0.0.3"},
+		{name: "missing version", packageJson: `{"name":"npm-example"}`, expectedError: true},
+		{name: "missing name", packageJson: `{"version":"0.0.3"}`, expectedError: true},
+		{name: "invalid json", packageJson: `{"name":`, expectedError: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			packageJsonPath := filepath.Join(t.TempDir(), "package.json")
+			assert.NoError(t, os.WriteFile(packageJsonPath, []byte(testCase.packageJson), 0644))
+			moduleId, err := ComputeNpmModuleId(packageJsonPath)
+			if testCase.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedModuleId, moduleId)
+		})
+	}
+
+	_, err := ComputeNpmModuleId(filepath.Join(t.TempDir(), "package.json"))
+	assert.Error(t, err)
+}
+
+func TestIsNpmrcArtifactoryConfigured(t *testing.T) {
+	const defaultExpectedRegistry = "https://acme.jfrog.io/artifactory/api/npm/npm-remote/"
+	testCases := []struct {
+		name               string
+		npmrc              string
+		expectedRegistry   string
+		expectedConfigured bool
+	}{
+		{name: "scoped auth token", npmrc: "registry=https://acme.jfrog.io/artifactory/api/npm/npm-remote/\n//acme.jfrog.io/artifactory/api/npm/npm-remote/:_authToken=token", expectedConfigured: true},
+		{name: "scoped basic auth without trailing slash", npmrc: "registry = https://acme.jfrog.io/artifactory/api/npm/npm-remote\n//acme.jfrog.io/artifactory/api/npm/npm-remote:_auth = dXNlcjpwYXNz", expectedConfigured: true},
+		{name: "registry with port", npmrc: "registry=http://localhost:8081/artifactory/api/npm/npm-remote/\n//localhost:8081/artifactory/api/npm/npm-remote/:_authToken=token", expectedRegistry: "http://localhost:8081/artifactory/api/npm/npm-remote/", expectedConfigured: true},
+		{name: "unscoped auth", npmrc: "registry=https://acme.jfrog.io/artifactory/api/npm/npm-remote/\n_auth=dXNlcjpwYXNz", expectedConfigured: true},
+		{name: "mismatching registry", npmrc: "registry=https://registry.npmjs.org/\n//acme.jfrog.io/artifactory/api/npm/npm-remote/:_authToken=token"},
+		{name: "auth of another registry", npmrc: "registry=https://acme.jfrog.io/artifactory/api/npm/npm-remote/\n//acme.jfrog.io/artifactory/api/npm/other-remote/:_authToken=token"},
+		{name: "missing auth", npmrc: "registry=https://acme.jfrog.io/artifactory/api/npm/npm-remote/\n# _authToken=token"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(workingDir, npmrcFileName), []byte(testCase.npmrc), 0644))
+			expectedRegistry := testCase.expectedRegistry
+			if expectedRegistry == "" {
+				expectedRegistry = defaultExpectedRegistry
+			}
+			configured, err := IsNpmrcArtifactoryConfigured(workingDir, expectedRegistry)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedConfigured, configured)
+		})
+	}
+
+	// Missing npmrc.
+	configured, err := IsNpmrcArtifactoryConfigured(t.TempDir(), defaultExpectedRegistry)
+	assert.NoError(t, err)
+	assert.False(t, configured)
+}
+
+func TestWithTempNpmrc(t *testing.T) {
+	tempNpmrc := []byte("registry = http://goodRegistry\n")
+	fnErr := errors.New("fn failed")
+	testCases := []struct {
+		name          string
+		originalNpmrc string
+		fn            func() error
+		panics        bool
+		expectedErr   error
+	}{
+		{name: "success", originalNpmrc: "registry = http://originalRegistry\n", fn: func() error { return nil }},
+		{name: "error", originalNpmrc: "registry = http://originalRegistry\n", fn: func() error { return fnErr }, expectedErr: fnErr},
+		{name: "panic", originalNpmrc: "registry = http://originalRegistry\n", fn: func() error { panic("fn panicked") }, panics: true},
+		{name: "no original npmrc", fn: func() error { return nil }},
+		{name: "no original npmrc and error", fn: func() error { return fnErr }, expectedErr: fnErr},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			workingDir := t.TempDir()
+			npmrcPath := filepath.Join(workingDir, npmrcFileName)
+			if testCase.originalNpmrc != "" {
+				assert.NoError(t, os.WriteFile(npmrcPath, []byte(testCase.originalNpmrc), 0644))
+			}
+			run := func() error {
+				return WithTempNpmrc(workingDir, tempNpmrc, func() error {
+					content, err := os.ReadFile(npmrcPath)
+					assert.NoError(t, err)
+					assert.Equal(t, generatedNpmrcMarker+"\n"+string(tempNpmrc), string(content))
+					return testCase.fn()
+				})
+			}
+			if testCase.panics {
+				assert.Panics(t, func() { _ = run() })
+			} else {
+				assert.ErrorIs(t, run(), testCase.expectedErr)
+			}
+
+			if testCase.originalNpmrc == "" {
+				assert.NoFileExists(t, npmrcPath)
+			} else {
+				content, err := os.ReadFile(npmrcPath)
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.originalNpmrc, string(content))
+			}
+			assert.NoFileExists(t, filepath.Join(workingDir, npmrcBackupFileName))
+		})
+	}
+}
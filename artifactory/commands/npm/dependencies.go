@@ -0,0 +1,912 @@
+package npm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jfrog/build-info-go/build"
+	biUtils "github.com/jfrog/build-info-go/build/utils"
+	"github.com/jfrog/build-info-go/entities"
+	buildInfoUtils "github.com/jfrog/build-info-go/utils"
+	commandUtils "github.com/jfrog/jfrog-cli-core/v2/artifactory/commands/utils"
+	buildUtils "github.com/jfrog/jfrog-cli-core/v2/common/build"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/io/fileutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// The module property which marks a module whose dependencies collection was interrupted.
+	incompleteModuleProperty = "incomplete"
+	// The module property which holds the npm command line which produced the build.
+	commandLineModuleProperty = "commandLine"
+	// The scope of the dependencies listed by 'npm ls' without a version, which marks them in the build-info.
+	versionlessDependencyScope = "peer-unknown"
+	// The scope of locally linked dependencies, which are saved in the build-info without checksums.
+	localDependencyScope = "local"
+)
+
+var errCollectionInterrupted = errors.New("the dependencies collection was interrupted")
+
+// A dependency collected by 'npm ls', with the details required for calculating its checksums.
+type npmDependency struct {
+	entities.Dependency
+	name      string
+	version   string
+	integrity string
+	optional  bool
+	// True if the dependency is a local package linked into node_modules, which has no tarball.
+	local bool
+}
+
+// Calculates the project's dependencies by running 'npm ls', and collects their checksums from the local npm cache.
+// If the given context is canceled while collecting the checksums, the dependencies collected so far are returned along with errCollectionInterrupted.
+func (nc *NpmCommand) calculateDependencies(ctx context.Context) ([]entities.Dependency, error) {
+	srcPath, npmFlags := nc.workingDirectory, extractNpmFlags(nc.npmArgs)
+	if nc.global {
+		npmFlags = getGlobalNpmLsFlags(nc.npmArgs)
+		var err error
+		if srcPath, err = nc.getGlobalPackagesDir(npmFlags); err != nil {
+			return nil, err
+		}
+	}
+	collectionLog := &collectionLogger{Log: log.Logger}
+	if nc.directOnly {
+		log.Info("Only the direct npm dependencies are collected.")
+	} else if nc.maxDepth != nil {
+		log.Warn(fmt.Sprintf("The npm dependencies tree is collected up to depth %d. The deeper dependencies are missing in the build-info.", *nc.maxDepth))
+	}
+	if nc.getCollectionStrategy() == NodeModulesScanCollectionStrategy {
+		return nc.calculateDependenciesFromNodeModules(ctx, srcPath, npmFlags)
+	}
+	npmLsFlags := nc.getNpmLsFlags(npmFlags)
+	lockfileOnly, err := nc.isLockfileCollection(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	dependenciesMap, err := biUtils.CalculateDependenciesMap(nc.executablePath, srcPath, nc.moduleId,
+		biUtils.NpmTreeDepListParam{Args: slices.Clone(npmLsFlags), IgnoreNodeModules: lockfileOnly}, collectionLog, false)
+	if err != nil && !lockfileOnly && nc.getCollectionStrategy() == AutoCollectionStrategy && isPackageLockExists(srcPath) {
+		log.Warn("Failed to collect the dependencies from node_modules, falling back to package-lock.json:", err.Error())
+		lockfileOnly = true
+		dependenciesMap, err = biUtils.CalculateDependenciesMap(nc.executablePath, srcPath, nc.moduleId,
+			biUtils.NpmTreeDepListParam{Args: slices.Clone(npmLsFlags), IgnoreNodeModules: true}, collectionLog, false)
+	}
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	if lockfileOnly {
+		// The next runs of 'npm ls' must read the same dependencies tree.
+		npmLsFlags = append(npmLsFlags, "--package-lock-only")
+	}
+	cacheLocation, err := biUtils.GetNpmConfigCache(nc.workingDirectory, nc.executablePath, npmFlags, collectionLog)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	if len(collectionLog.warnings) > 0 {
+		// The warnings may be caused by 'npm ls' failing, which it does if the tree has problems, such as missing or invalid dependencies.
+		if err = nc.reportNpmLsProblems(npmLsFlags); err != nil {
+			return nil, err
+		}
+	}
+	if err = nc.validateStrictCollection("the following warnings were logged while collecting the dependencies", collectionLog.warnings); err != nil {
+		return nil, err
+	}
+	var dependencies []npmDependency
+	var missingPeerDeps, missingBundledDeps []string
+	for _, dep := range dependenciesMap {
+		if dep.Integrity == "" && dep.InBundle {
+			missingBundledDeps = append(missingBundledDeps, dep.Id)
+			continue
+		}
+		if dep.Integrity == "" && dep.PeerMissing != nil {
+			missingPeerDeps = append(missingPeerDeps, dep.Id)
+			continue
+		}
+		if nc.productionOnly && !slices.Contains(dep.Scopes, "prod") {
+			continue
+		}
+		// 'npm ls --depth=0' lists only the direct dependencies, but they're filtered anyway, in case npm lists deeper dependencies.
+		if nc.directOnly && !isDirectDependency(dep.RequestedBy) {
+			continue
+		}
+		dependencies = append(dependencies, npmDependency{Dependency: dep.Dependency, name: dep.Name, version: dep.Version, integrity: dep.Integrity, optional: dep.Optional})
+	}
+	if nc.global {
+		dependencies = filterInstalledGlobalDependencies(dependencies, filterFlags(nc.npmArgs))
+	}
+	if err = nc.validateStrictCollection("the following peer dependencies are missing", missingPeerDeps); err != nil {
+		return nil, err
+	}
+	printSkippedDependencies("peerDependency", missingPeerDeps)
+	printSkippedDependencies("bundleDependencies", missingBundledDeps)
+	if dependencies, err = nc.limitDependencies(dependencies); err != nil {
+		return nil, err
+	}
+	resolvedUrls := nc.getResolvedUrlsWithoutIntegrity(dependencies, npmLsFlags)
+	markLocalDependencies(dependencies, resolvedUrls)
+	resolveMissingIntegrities(dependencies, resolvedUrls, cacheLocation)
+	if nc.verifyAgainstLockfile {
+		if nc.lockfileIntegrities, err = readLockfileIntegrities(srcPath); err != nil {
+			return nil, err
+		}
+	}
+	collectedDependencies, err := nc.collectDependenciesChecksums(ctx, dependencies, commandUtils.NewNpmCacheTarballLocator(cacheLocation))
+	if err == nil && nc.collectRelationships {
+		nc.collectDependencyRelationships(npmLsFlags, collectedDependencies)
+	}
+	if err != nil || !nc.includeVersionlessDeps {
+		return collectedDependencies, err
+	}
+	versionlessDependencies, err := nc.collectVersionlessDependencies(npmLsFlags)
+	if err != nil {
+		return nil, err
+	}
+	return append(collectedDependencies, versionlessDependencies...), nil
+}
+
+// Applies the maximum number of dependencies, if set. Exceeding it returns an error, or the first dependencies by their IDs if truncating is allowed.
+func (nc *NpmCommand) limitDependencies(dependencies []npmDependency) ([]npmDependency, error) {
+	if nc.maxDependencies <= 0 || len(dependencies) <= nc.maxDependencies {
+		return dependencies, nil
+	}
+	if !nc.truncateDependencies {
+		return nil, errorutils.CheckErrorf("the project has %d dependencies, which exceeds the maximum of %d dependencies collected for the build-info. "+
+			"Increase the maximum, or allow truncating the dependencies", len(dependencies), nc.maxDependencies)
+	}
+	log.Warn(fmt.Sprintf("The project has %d dependencies, which exceeds the maximum of %d. Only the first %d dependencies are saved in the build-info.",
+		len(dependencies), nc.maxDependencies, nc.maxDependencies))
+	sort.Slice(dependencies, func(i, j int) bool {
+		return dependencies[i].Id < dependencies[j].Id
+	})
+	return dependencies[:nc.maxDependencies], nil
+}
+
+func (nc *NpmCommand) getCollectionStrategy() CollectionStrategy {
+	if nc.collectionStrategy == "" {
+		return AutoCollectionStrategy
+	}
+	return nc.collectionStrategy
+}
+
+// Returns true if the dependencies tree should be read from package-lock.json rather than from node_modules, according to the collection strategy.
+func (nc *NpmCommand) isLockfileCollection(srcPath string) (bool, error) {
+	nodeModulesExists, err := fileutils.IsDirExists(filepath.Join(srcPath, "node_modules"), false)
+	if err != nil {
+		return false, err
+	}
+	switch nc.getCollectionStrategy() {
+	case AutoCollectionStrategy:
+		return !nodeModulesExists, nil
+	case NpmLsCollectionStrategy:
+		if !nodeModulesExists {
+			return false, errorutils.CheckErrorf("the '%s' collection strategy requires the installed node_modules, which is missing in %s", NpmLsCollectionStrategy, srcPath)
+		}
+		return false, nil
+	case LockfileCollectionStrategy:
+		if nc.global {
+			return false, errorutils.CheckErrorf("the '%s' collection strategy isn't supported for global packages", LockfileCollectionStrategy)
+		}
+		if !isPackageLockExists(srcPath) {
+			return false, errorutils.CheckErrorf("the '%s' collection strategy requires package-lock.json, which is missing in %s", LockfileCollectionStrategy, srcPath)
+		}
+		return true, nil
+	default:
+		return false, errorutils.CheckErrorf("unsupported npm collection strategy '%s'", nc.collectionStrategy)
+	}
+}
+
+func isPackageLockExists(srcPath string) bool {
+	exists, err := fileutils.IsFileExists(filepath.Join(srcPath, "package-lock.json"), false)
+	return err == nil && exists
+}
+
+// Returns the flags for running 'npm ls', with the depth flag if a max depth is set.
+// 'npm ls' is also run with '--all' to list the full tree, but an explicit depth flag takes precedence over it.
+func (nc *NpmCommand) getNpmLsFlags(npmFlags []string) []string {
+	maxDepth := nc.getMaxDepth()
+	if maxDepth == nil {
+		return npmFlags
+	}
+	return append(slices.Clone(npmFlags), fmt.Sprintf("--depth=%d", *maxDepth))
+}
+
+// Returns the max depth of the collected dependencies tree, which is 0 if only the direct dependencies are collected, or nil for the full tree.
+func (nc *NpmCommand) getMaxDepth() *int {
+	if nc.directOnly {
+		return clientutils.Pointer(0)
+	}
+	return nc.maxDepth
+}
+
+// Returns true if the dependency is requested by the project itself, which is the only element of the dependency's requestedBy path.
+func isDirectDependency(requestedBy [][]string) bool {
+	return slices.ContainsFunc(requestedBy, func(path []string) bool { return len(path) == 1 })
+}
+
+// Returns the flags for running 'npm ls' on the global packages.
+// Package arguments would make 'npm ls' list only the paths to these packages, without their dependencies, so only the flags in the '--key=value'
+// form (or without a value) are kept, since the values of flags in the '--key value' form can't be told apart from package arguments.
+func getGlobalNpmLsFlags(npmArgs []string) []string {
+	npmLsFlags := []string{"--global"}
+	for _, arg := range npmArgs {
+		if strings.HasPrefix(arg, "-") && !isGlobalInstall([]string{arg}) {
+			npmLsFlags = append(npmLsFlags, arg)
+		}
+	}
+	return npmLsFlags
+}
+
+// Returns the directory that contains the global node_modules directory, in which 'npm ls' runs to collect the global packages.
+func (nc *NpmCommand) getGlobalPackagesDir(npmFlags []string) (string, error) {
+	output, _, err := biUtils.RunNpmCmd(nc.executablePath, nc.workingDirectory, append([]string{"root"}, npmFlags...), log.Logger)
+	if err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	return filepath.Dir(strings.TrimSpace(string(output))), nil
+}
+
+// 'npm ls -g' lists all the global packages. Keeps only the dependencies of the installed packages, whose names are taken from the given package arguments.
+// If none of the arguments is the name of a global package (for example, when installing from a tarball), all the dependencies are kept.
+func filterInstalledGlobalDependencies(dependencies []npmDependency, packageArgs []string) []npmDependency {
+	installedPackages := make(map[string]bool)
+	for _, packageArg := range packageArgs {
+		installedPackages[getPackageName(packageArg)] = true
+	}
+	var filteredDependencies []npmDependency
+	for _, dep := range dependencies {
+		for _, path := range dep.RequestedBy {
+			// The global package through which the dependency was installed, which is requested by the module itself.
+			topLevelPackage := dep.name
+			if len(path) > 1 {
+				topLevelId := path[len(path)-2]
+				topLevelPackage = topLevelId[:max(strings.LastIndex(topLevelId, ":"), 0)]
+			}
+			if installedPackages[topLevelPackage] {
+				filteredDependencies = append(filteredDependencies, dep)
+				break
+			}
+		}
+	}
+	if len(filteredDependencies) == 0 {
+		log.Debug("None of the arguments is the name of a global package. All the global packages are collected.")
+		return dependencies
+	}
+	return filteredDependencies
+}
+
+// Returns the package name of the given package spec. For example: 'typescript' for 'typescript@5.0.0', or '@jfrog/pkg' for '@jfrog/pkg@1.0.0'.
+func getPackageName(packageSpec string) string {
+	if i := strings.LastIndex(packageSpec, "@"); i > 0 {
+		return packageSpec[:i]
+	}
+	return packageSpec
+}
+
+// An entry of the dependencies tree returned by 'npm ls --json'.
+type npmLsEntry struct {
+	Version      string                 `json:"version,omitempty"`
+	Resolved     string                 `json:"resolved,omitempty"`
+	Dependencies map[string]*npmLsEntry `json:"dependencies,omitempty"`
+}
+
+// Runs 'npm ls' and returns the dependencies it listed without a version (such as peer dependencies which were not installed).
+// These dependencies are skipped while calculating the dependencies map, so they are collected separately.
+func (nc *NpmCommand) collectVersionlessDependencies(npmLsFlags []string) ([]entities.Dependency, error) {
+	output, err := nc.runNpmLs(npmLsFlags)
+	if err != nil {
+		return nil, err
+	}
+	versionlessDependencies, err := parseVersionlessDependencies(output, nc.moduleId)
+	if err != nil {
+		return nil, err
+	}
+	if len(versionlessDependencies) > 0 {
+		var ids []string
+		for _, dep := range versionlessDependencies {
+			ids = append(ids, dep.Id)
+		}
+		log.Warn(fmt.Sprintf("The following dependencies have no version in the 'npm ls' output, and are added to the build-info with the '%s' scope:\n%s",
+			versionlessDependencyScope, strings.Join(ids, "\n")))
+	}
+	return versionlessDependencies, nil
+}
+
+const npmLsMaxRetries = 3
+
+var (
+	// Allows mocking the npm commands in tests.
+	runNpmCmd = biUtils.RunNpmCmd
+	// The wait before the first retry of 'npm ls'. The wait is doubled before each further retry.
+	npmLsRetryInitialWait = 250 * time.Millisecond
+)
+
+// Runs 'npm ls --json' and returns the dependencies tree it printed.
+// Right after an installation, 'npm ls' may fail transiently (for example with ENOENT, while the tree is still being written),
+// so failures are retried with an exponential backoff. If all the retries fail, the error of the last attempt, including npm's stderr, is returned.
+func (nc *NpmCommand) runNpmLs(npmLsFlags []string) (output []byte, err error) {
+	npmArgs := []string{"ls", "--json"}
+	if nc.getMaxDepth() == nil {
+		npmArgs = append(npmArgs, "--all")
+	}
+	npmArgs = append(npmArgs, npmLsFlags...)
+	wait := npmLsRetryInitialWait
+	for attempt := 0; ; attempt++ {
+		output, _, err = runNpmCmd(nc.executablePath, nc.workingDirectory, npmArgs, log.Logger)
+		// 'npm ls' fails when it encounters problems such as missing dependencies, but still prints the dependencies tree.
+		if err == nil || len(output) > 0 {
+			return output, nil
+		}
+		if attempt == npmLsMaxRetries {
+			return nil, errorutils.CheckErrorf("'npm ls' failed after %d attempts: %s", attempt+1, err.Error())
+		}
+		log.Debug(fmt.Sprintf("'npm ls' failed (attempt %d). Retrying in %s: %s", attempt+1, wait, err.Error()))
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// Reads the problems of the dependencies tree listed by 'npm ls', which may make the build-info incomplete, and logs them as warnings.
+// In strict collection mode, problems fail the collection. Failing to read the problems doesn't fail the collection.
+func (nc *NpmCommand) reportNpmLsProblems(npmLsFlags []string) error {
+	output, err := nc.runNpmLs(npmLsFlags)
+	if err != nil {
+		log.Debug("Couldn't read the problems of the dependencies tree:", err.Error())
+		return nil
+	}
+	problems, err := parseNpmLsProblems(output)
+	if err != nil {
+		log.Debug("Couldn't parse the problems of the dependencies tree:", err.Error())
+		return nil
+	}
+	if err = nc.validateStrictCollection("'npm ls' reported the following problems", problems); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		log.Warn("'npm ls' reported the following problems, which may leave dependencies out of the build-info:\n" + strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// Returns the problems listed at the top level of the 'npm ls --json' output (npm 7 and above), which include the problems of all the dependencies.
+func parseNpmLsProblems(npmLsOutput []byte) ([]string, error) {
+	var root struct {
+		Problems []string `json:"problems,omitempty"`
+	}
+	if err := json.Unmarshal(npmLsOutput, &root); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	return root.Problems, nil
+}
+
+// Returns the tarball URLs (the 'resolved' fields in 'npm ls') of the dependencies, by their IDs, if any of the dependencies has no integrity.
+// Since getting the URLs requires running 'npm ls' again, nil is returned if all the dependencies have an integrity, or if 'npm ls' fails.
+func (nc *NpmCommand) getResolvedUrlsWithoutIntegrity(dependencies []npmDependency, npmLsFlags []string) map[string]string {
+	if !slices.ContainsFunc(dependencies, func(dep npmDependency) bool { return dep.integrity == "" }) {
+		return nil
+	}
+	output, err := nc.runNpmLs(npmLsFlags)
+	if err == nil {
+		var resolvedUrls map[string]string
+		if resolvedUrls, err = parseResolvedUrls(output); err == nil {
+			return resolvedUrls
+		}
+	}
+	log.Debug("Couldn't get the tarball URLs of the dependencies without an integrity:", err.Error())
+	return nil
+}
+
+// Workspace symlinks and local directories linked into node_modules are resolved by 'link:' or 'file:' URLs, and have no tarball in the npm cache.
+// These dependencies are marked as local, so that they are saved in the build-info with the local scope instead of being reported as missing.
+// Local tarballs ('file:' URLs of tarballs) are cached by npm, so their checksums are still calculated.
+func markLocalDependencies(dependencies []npmDependency, resolvedUrls map[string]string) {
+	for i := range dependencies {
+		if dependencies[i].integrity != "" || !isLocalLink(resolvedUrls[dependencies[i].Id]) {
+			continue
+		}
+		dependencies[i].local = true
+		dependencies[i].Scopes = append(dependencies[i].Scopes, localDependencyScope)
+	}
+}
+
+func isLocalLink(resolvedUrl string) bool {
+	if strings.HasPrefix(resolvedUrl, "link:") {
+		return true
+	}
+	localPath, isFile := strings.CutPrefix(resolvedUrl, "file:")
+	return isFile && !strings.HasSuffix(localPath, ".tgz") && !strings.HasSuffix(localPath, ".tar.gz") && !strings.HasSuffix(localPath, ".tar")
+}
+
+// Dependencies without an integrity (for example, if package-lock.json lost it) are looked up in the npm cache by their name and version,
+// which may match the tarball of another package with the same name and version, served by another registry.
+// For these dependencies, the integrity is looked up in the npm cache by their exact tarball URL, if it's known.
+// Dependencies whose integrity isn't found this way are looked up by their name and version, as before.
+func resolveMissingIntegrities(dependencies []npmDependency, resolvedUrls map[string]string, cacheLocation string) {
+	for i := range dependencies {
+		resolvedUrl := resolvedUrls[dependencies[i].Id]
+		if dependencies[i].integrity != "" || dependencies[i].local || resolvedUrl == "" {
+			continue
+		}
+		integrity, err := commandUtils.GetNpmCacheIntegrityByResolvedUrl(cacheLocation, resolvedUrl)
+		if err != nil {
+			log.Debug("Couldn't find", dependencies[i].Id, "in the npm cache by its tarball URL. Looking it up by its name and version:", err.Error())
+			continue
+		}
+		dependencies[i].integrity = integrity
+	}
+}
+
+// Parses the output of 'npm ls --json' and returns the tarball URLs (the 'resolved' fields) of the dependencies, by their IDs.
+func parseResolvedUrls(npmLsOutput []byte) (map[string]string, error) {
+	root := new(npmLsEntry)
+	if err := json.Unmarshal(npmLsOutput, root); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	resolvedUrls := make(map[string]string)
+	var walk func(entry *npmLsEntry)
+	walk = func(entry *npmLsEntry) {
+		for name, child := range entry.Dependencies {
+			if child == nil || child.Version == "" {
+				continue
+			}
+			if child.Resolved != "" {
+				resolvedUrls[name+":"+child.Version] = child.Resolved
+			}
+			walk(child)
+		}
+	}
+	walk(root)
+	return resolvedUrls, nil
+}
+
+// Parses the output of 'npm ls --json' and returns the dependencies listed without a version.
+// Each dependency is returned once, with an empty version and the versionless dependency scope, and with all the paths in which it was requested.
+func parseVersionlessDependencies(npmLsOutput []byte, moduleId string) ([]entities.Dependency, error) {
+	root := new(npmLsEntry)
+	if err := json.Unmarshal(npmLsOutput, root); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	dependenciesMap := make(map[string]*entities.Dependency)
+	var walk func(entry *npmLsEntry, requestedBy []string)
+	walk = func(entry *npmLsEntry, requestedBy []string) {
+		for name, child := range entry.Dependencies {
+			if child == nil {
+				continue
+			}
+			if child.Version == "" {
+				id := name + ":"
+				dep, exists := dependenciesMap[id]
+				if !exists {
+					dep = &entities.Dependency{Id: id, Scopes: []string{versionlessDependencyScope}}
+					dependenciesMap[id] = dep
+				}
+				dep.RequestedBy = append(dep.RequestedBy, requestedBy)
+				continue
+			}
+			walk(child, append([]string{name + ":" + child.Version}, requestedBy...))
+		}
+	}
+	walk(root, []string{moduleId})
+	var dependencies []entities.Dependency
+	for _, dep := range dependenciesMap {
+		dependencies = append(dependencies, *dep)
+	}
+	sortDependencies(dependencies)
+	return dependencies, nil
+}
+
+// Calculates the checksums of the given dependencies from their tarballs in the local npm cache.
+// If checksum scopes were set, only the checksums of dependencies with at least one of these scopes are calculated.
+// The other dependencies are returned without checksums.
+// Dependencies whose tarballs could not be found are not returned.
+// If the given context is canceled, the dependencies collected so far are returned along with errCollectionInterrupted.
+func (nc *NpmCommand) collectDependenciesChecksums(ctx context.Context, dependencies []npmDependency, locateTarball commandUtils.NpmCacheTarballLocator) ([]entities.Dependency, error) {
+	var dependenciesList []entities.Dependency
+	var missingOptionalDeps, otherMissingDeps []string
+	// The checksums calculation errors of all the dependencies are returned together, rather than only the first one.
+	var checksumErrors []error
+	var integrityMismatches []string
+	for _, dep := range dependencies {
+		if ctx.Err() != nil {
+			return dependenciesList, errCollectionInterrupted
+		}
+		if dep.local {
+			log.Debug("Skipping checksums calculation for " + dep.Id + ", as it's a locally linked package.")
+			dependenciesList = append(dependenciesList, dep.Dependency)
+			continue
+		}
+		if !nc.isChecksumRequired(dep.Scopes) {
+			log.Debug("Skipping checksums calculation for " + dep.Id + ", as it doesn't have any of the scopes: " + strings.Join(nc.checksumScopes, ","))
+			dependenciesList = append(dependenciesList, dep.Dependency)
+			continue
+		}
+		tarballPath, err := locateTarball(dep.name, dep.version, dep.integrity)
+		if err == nil {
+			var checksum *entities.Checksum
+			if checksum, err = commandUtils.CalculateFileChecksum(tarballPath); err != nil {
+				checksumErrors = append(checksumErrors, fmt.Errorf("failed to calculate the checksums of %s: %w", dep.Id, err))
+				continue
+			}
+			dep.Checksum = *checksum
+			if integrity, exists := nc.lockfileIntegrities[dep.Id]; exists {
+				if err = verifyIntegrity(tarballPath, dep.Checksum, integrity); err != nil {
+					integrityMismatches = append(integrityMismatches, dep.Id+": "+err.Error())
+				}
+			}
+			if nc.collectLicenses {
+				nc.collectLicense(dep.Id, tarballPath)
+			}
+		} else {
+			if dep.optional {
+				missingOptionalDeps = append(missingOptionalDeps, dep.Id)
+				continue
+			}
+			// The tarball may be missing if the dependencies lost their integrity, for example when package-lock.json was upgraded from lockfileVersion 1 to 2.
+			otherMissingDeps = append(otherMissingDeps, dep.Id)
+			log.Debug("Couldn't calculate checksums for " + dep.Id + ". Error: '" + err.Error() + "'.")
+			continue
+		}
+		dependenciesList = append(dependenciesList, dep.Dependency)
+	}
+	if len(checksumErrors) > 0 {
+		return nil, errors.Join(checksumErrors...)
+	}
+	if len(integrityMismatches) > 0 {
+		return nil, errorutils.CheckErrorf("the checksums of the following dependencies don't match their integrity in package-lock.json:\n%s", strings.Join(integrityMismatches, "\n"))
+	}
+	nc.result.MissingDependencies = append(nc.result.MissingDependencies, append(missingOptionalDeps, otherMissingDeps...)...)
+	printSkippedDependencies("optionalDependencies", missingOptionalDeps)
+	if err := nc.validateStrictCollection("the following dependencies are missing in the npm cache", otherMissingDeps); err != nil {
+		return nil, err
+	}
+	if len(otherMissingDeps) > 0 {
+		log.Warn("The following dependencies will not be included in the build-info, because they are missing in the npm cache: '" + strings.Join(otherMissingDeps, ",") + "'.\nHint: Try deleting 'node_modules' and/or 'package-lock.json'.")
+	}
+	return dependenciesList, nil
+}
+
+// In strict collection mode, an incomplete dependencies collection fails the command instead of producing an incomplete build-info.
+// Returns an error if strict collection is enabled and issues were found.
+func (nc *NpmCommand) validateStrictCollection(issuesDescription string, issues []string) error {
+	if !nc.strictCollection || len(issues) == 0 {
+		return nil
+	}
+	return errorutils.CheckErrorf("strict dependencies collection failed, %s:\n%s", issuesDescription, strings.Join(issues, "\n"))
+}
+
+// Returns true if the checksums of a dependency with the given scopes should be calculated.
+func (nc *NpmCommand) isChecksumRequired(scopes []string) bool {
+	if len(nc.checksumScopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		if slices.Contains(nc.checksumScopes, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+func printSkippedDependencies(dependencyType string, dependencies []string) {
+	if len(dependencies) == 0 {
+		return
+	}
+	log.Debug("The following dependencies will not be included in the build-info, because their integrity could not be found.\n" +
+		"The reason may be that the package is a '" + dependencyType + "', which was not manually installed.\n" +
+		"It is therefore okay to skip these dependencies: " + strings.Join(dependencies, ","))
+}
+
+// Saves the given dependencies as the npm module of the build-info.
+// If a dependency transform function was set, it is applied on the dependencies before they are saved.
+func (nc *NpmCommand) saveDependenciesData(dependencies []entities.Dependency) (err error) {
+	var mergedFiles []string
+	if nc.mergeModule {
+		if dependencies, mergedFiles, err = nc.mergeWithSavedModule(dependencies); err != nil {
+			return
+		}
+	}
+	sortDependencies(dependencies)
+	dependencies = applyDuplicateVersionPolicy(dependencies, nc.duplicateVersionPolicy)
+	if nc.dependencyTransform != nil {
+		dependencies = nc.dependencyTransform(dependencies)
+	}
+	nc.dependencies = dependencies
+	buildInfoModule := entities.Module{Id: nc.moduleId, Type: entities.Npm, Dependencies: dependencies}
+	if moduleProperties := nc.getModuleProperties(); moduleProperties != nil {
+		buildInfoModule.Properties = moduleProperties
+	}
+	if nc.attachLockfile {
+		var lockfileArtifact *entities.Artifact
+		if lockfileArtifact, err = nc.getLockfileArtifact(); err != nil {
+			return
+		}
+		if lockfileArtifact != nil {
+			buildInfoModule.Artifacts = []entities.Artifact{*lockfileArtifact}
+		}
+	}
+	buildInfo := &entities.BuildInfo{Modules: []entities.Module{buildInfoModule}}
+	if nc.buildInfoWriter != nil {
+		if err = writeBuildInfo(nc.buildInfoWriter, buildInfo); err != nil {
+			return
+		}
+	}
+	nc.result.DependenciesCount += len(dependencies)
+	if nc.skipBuildInfoPartial {
+		return
+	}
+	if err = nc.npmBuild.SaveBuildInfo(buildInfo); err != nil {
+		return errorutils.CheckError(err)
+	}
+	if nc.buildInfoDir != "" {
+		if err = nc.saveBuildInfoCopy(buildInfo); err != nil {
+			return
+		}
+	}
+	nc.result.BuildInfoSaved = true
+	// The merged files are removed only after the merged module is saved, to avoid losing dependencies if the saving fails.
+	for _, mergedFile := range mergedFiles {
+		err = errors.Join(err, errorutils.CheckError(os.Remove(mergedFile)))
+	}
+	return
+}
+
+// Returns the project's package-lock.json as a build-info artifact, or nil if the project has no package-lock.json.
+func (nc *NpmCommand) getLockfileArtifact() (*entities.Artifact, error) {
+	lockfilePath := filepath.Join(nc.workingDirectory, "package-lock.json")
+	exists, err := fileutils.IsFileExists(lockfilePath, false)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		log.Debug("The project has no package-lock.json to attach to the build-info.")
+		return nil, nil
+	}
+	checksum, err := commandUtils.CalculateFileChecksum(lockfilePath)
+	if err != nil {
+		return nil, err
+	}
+	return &entities.Artifact{Name: "package-lock.json", Type: "json", Checksum: *checksum}, nil
+}
+
+func writeBuildInfo(writer io.Writer, buildInfo *entities.BuildInfo) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return errorutils.CheckError(encoder.Encode(buildInfo))
+}
+
+// Merges the given dependencies with the dependencies of the npm module with the same ID, saved in the build directory by previous runs.
+// Returns the merged dependencies, and the build-info files from which they were merged, which should be removed once the merged module is saved.
+func (nc *NpmCommand) mergeWithSavedModule(dependencies []entities.Dependency) (mergedDependencies []entities.Dependency, mergedFiles []string, err error) {
+	buildDir, err := nc.getBuildDir()
+	if err != nil {
+		return
+	}
+	buildFiles, err := fileutils.ListFiles(buildDir, false)
+	if err != nil {
+		return
+	}
+	mergedDependencies = dependencies
+	for _, buildFile := range buildFiles {
+		var isDir bool
+		if isDir, err = fileutils.IsDirExists(buildFile, false); err != nil {
+			return nil, nil, err
+		}
+		if isDir {
+			continue
+		}
+		var content []byte
+		if content, err = os.ReadFile(buildFile); err != nil {
+			return nil, nil, errorutils.CheckError(err)
+		}
+		savedBuildInfo := new(entities.BuildInfo)
+		if err = json.Unmarshal(content, savedBuildInfo); err != nil {
+			return nil, nil, errorutils.CheckError(err)
+		}
+		// Only build-info files holding this module alone, as saved by this command, are merged.
+		if len(savedBuildInfo.Modules) != 1 || savedBuildInfo.Modules[0].Id != nc.moduleId || savedBuildInfo.Modules[0].Type != entities.Npm {
+			continue
+		}
+		log.Debug("Merging the dependencies of the module " + nc.moduleId + " saved in " + buildFile)
+		mergedDependencies = mergeDependencies(savedBuildInfo.Modules[0].Dependencies, mergedDependencies)
+		if savedProperties, ok := savedBuildInfo.Modules[0].Properties.(map[string]interface{}); ok {
+			for key, value := range savedProperties {
+				// The custom properties of this run replace the saved ones.
+				_, isCustom := nc.customModuleProperties[key]
+				if _, exists := nc.moduleProperties[key]; !exists && !isCustom {
+					nc.setModuleProperty(key, fmt.Sprint(value))
+				}
+			}
+		}
+		mergedFiles = append(mergedFiles, buildFile)
+	}
+	return
+}
+
+// Returns the directory in which the build-info files of the current build are saved.
+func (nc *NpmCommand) getBuildDir() (string, error) {
+	buildName, err := nc.buildConfiguration.GetBuildName()
+	if err != nil {
+		return "", err
+	}
+	buildNumber, err := nc.buildConfiguration.GetBuildNumber()
+	if err != nil {
+		return "", err
+	}
+	buildDir, err := buildInfoUtils.GetBuildDir(buildName, buildNumber, nc.buildConfiguration.GetProject(), getBuildsDirPath())
+	return buildDir, errorutils.CheckError(err)
+}
+
+func getBuildsDirPath() string {
+	return filepath.Join(coreutils.GetCliPersistentTempDirPath(), buildUtils.BuildTempPath)
+}
+
+// Saves a copy of the build-info in the build-info directory, with the same layout as in the default directory, from which it's published.
+func (nc *NpmCommand) saveBuildInfoCopy(buildInfo *entities.BuildInfo) error {
+	buildName, err := nc.buildConfiguration.GetBuildName()
+	if err != nil {
+		return err
+	}
+	buildNumber, err := nc.buildConfiguration.GetBuildNumber()
+	if err != nil {
+		return err
+	}
+	buildInfoService := buildUtils.CreateBuildInfoService()
+	buildInfoService.SetTempDirPath(nc.buildInfoDir)
+	buildCopy, err := buildInfoService.GetOrCreateBuildWithProject(buildName, buildNumber, nc.buildConfiguration.GetProject())
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	return errorutils.CheckError(buildCopy.SaveBuildInfo(buildInfo))
+}
+
+// Saves the fixed build timestamp in the general details of the build, which are read when the build-info is published.
+func (nc *NpmCommand) saveBuildTimestamp(buildName, buildNumber string) error {
+	partialsBuildDir, err := buildInfoUtils.GetPartialsBuildDir(buildName, buildNumber, nc.buildConfiguration.GetProject(), getBuildsDirPath())
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	content, err := json.Marshal(&entities.General{Timestamp: nc.buildTimestamp})
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	return errorutils.CheckError(os.WriteFile(filepath.Join(partialsBuildDir, build.BuildInfoDetails), content, 0600))
+}
+
+// Merges two lists of dependencies, deduplicating dependencies with the same ID.
+// The scopes and requestedBy paths of duplicate dependencies are combined, and their checksums are taken from the newer dependency if it has them.
+func mergeDependencies(dependencies, newDependencies []entities.Dependency) []entities.Dependency {
+	mergedDependencies := slices.Clone(dependencies)
+	indexById := make(map[string]int, len(mergedDependencies))
+	for i, dependency := range mergedDependencies {
+		indexById[dependency.Id] = i
+	}
+	for _, newDependency := range newDependencies {
+		i, exists := indexById[newDependency.Id]
+		if !exists {
+			indexById[newDependency.Id] = len(mergedDependencies)
+			mergedDependencies = append(mergedDependencies, newDependency)
+			continue
+		}
+		merged := &mergedDependencies[i]
+		for _, scope := range newDependency.Scopes {
+			if !slices.Contains(merged.Scopes, scope) {
+				merged.Scopes = append(merged.Scopes, scope)
+			}
+		}
+		for _, requestedBy := range newDependency.RequestedBy {
+			if !slices.ContainsFunc(merged.RequestedBy, func(path []string) bool { return slices.Equal(path, requestedBy) }) {
+				merged.RequestedBy = append(merged.RequestedBy, requestedBy)
+			}
+		}
+		if !newDependency.Checksum.IsEmpty() {
+			merged.Checksum = newDependency.Checksum
+		}
+	}
+	return mergedDependencies
+}
+
+// Saves the given dependencies, collected before the collection was interrupted, with the module marked as incomplete.
+func (nc *NpmCommand) saveIncompleteDependenciesData(dependencies []entities.Dependency) error {
+	nc.setModuleProperty(incompleteModuleProperty, "true")
+	return nc.saveDependenciesData(dependencies)
+}
+
+// Runs 'npm ls --long', and saves the relationship types of the edges of the dependencies tree in the module properties.
+// Only the edges of the given dependencies are saved. Failing to read the relationships doesn't fail the dependencies collection.
+func (nc *NpmCommand) collectDependencyRelationships(npmLsFlags []string, dependencies []entities.Dependency) {
+	output, err := nc.runNpmLs(append(slices.Clone(npmLsFlags), "--long"))
+	if err != nil {
+		log.Warn("Couldn't read the relationships of the dependencies:", err.Error())
+		return
+	}
+	relationships, err := parseDependencyRelationships(output, nc.moduleId)
+	if err != nil {
+		log.Warn("Couldn't parse the relationships of the dependencies:", err.Error())
+		return
+	}
+	collectedIds := make(map[string]bool, len(dependencies))
+	for _, dep := range dependencies {
+		collectedIds[dep.Id] = true
+	}
+	for edge, relationship := range relationships {
+		if collectedIds[edge.dependencyId] {
+			nc.setModuleProperty(edge.modulePropertyKey(), relationship)
+		}
+	}
+}
+
+// Reads the license of the dependency from its tarball, and saves it in the module properties.
+// Failing to read the license doesn't fail the dependencies collection.
+func (nc *NpmCommand) collectLicense(dependencyId, tarballPath string) {
+	license, err := readLicenseFromTarball(tarballPath)
+	if err != nil {
+		log.Debug("Couldn't read the license of " + dependencyId + ". Error: '" + err.Error() + "'.")
+		return
+	}
+	if license != "" {
+		nc.setModuleProperty(licenseModulePropertyPrefix+dependencyId, license)
+	}
+}
+
+// Returns the custom module properties, merged with the properties collected by the command, or nil if there are no properties.
+func (nc *NpmCommand) getModuleProperties() map[string]string {
+	if len(nc.customModuleProperties) == 0 {
+		return nc.moduleProperties
+	}
+	moduleProperties := maps.Clone(nc.customModuleProperties)
+	maps.Copy(moduleProperties, nc.moduleProperties)
+	return moduleProperties
+}
+
+func (nc *NpmCommand) setModuleProperty(key, value string) {
+	if nc.moduleProperties == nil {
+		nc.moduleProperties = make(map[string]string)
+	}
+	nc.moduleProperties[key] = value
+}
+
+// The dependencies are collected from a map, so their order is random.
+// Sort the dependencies by their ID, as well as their scopes and requestedBy paths, to keep the saved build-info deterministic.
+func sortDependencies(dependencies []entities.Dependency) {
+	for i := range dependencies {
+		sort.Strings(dependencies[i].Scopes)
+		requestedBy := dependencies[i].RequestedBy
+		sort.Slice(requestedBy, func(j, k int) bool {
+			return strings.Join(requestedBy[j], ",") < strings.Join(requestedBy[k], ",")
+		})
+	}
+	sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].Id < dependencies[j].Id })
+}
+
+// Returns the flags of the npm command, starting from the first flag. The npm command and its positional arguments are discarded.
+func extractNpmFlags(npmArgs []string) []string {
+	for i, arg := range npmArgs {
+		if strings.HasPrefix(arg, "-") {
+			return npmArgs[i:]
+		}
+	}
+	return []string{}
+}
+
+// A logger which records the warnings logged while collecting the dependencies, in addition to logging them.
+type collectionLogger struct {
+	log.Log
+	warnings []string
+}
+
+func (cl *collectionLogger) Warn(a ...interface{}) {
+	cl.warnings = append(cl.warnings, strings.TrimSpace(fmt.Sprintln(a...)))
+	cl.Log.Warn(a...)
+}
@@ -0,0 +1,778 @@
+package npm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jfrog/build-info-go/build"
+	biutils "github.com/jfrog/build-info-go/build/utils"
+	"github.com/jfrog/build-info-go/entities"
+	buildInfoUtils "github.com/jfrog/build-info-go/utils"
+	buildUtils "github.com/jfrog/jfrog-cli-core/v2/common/build"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/tests"
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slices"
+)
+
+func TestSortDependencies(t *testing.T) {
+	expected := []entities.Dependency{
+		{Id: "debug:4.1.1", Scopes: []string{"dev"}, RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+		{Id: "ms:2.0.0", Scopes: []string{"@jfrog", "prod"}, RequestedBy: [][]string{{"debug:2.6.9", "send:0.16.2", "npm-example:0.0.3"}, {"send:0.16.2", "npm-example:0.0.3"}}},
+		{Id: "send:0.16.2", Scopes: []string{"prod"}, RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+	}
+	// Run several times on differently ordered inputs, to make sure the output is always the same.
+	inputs := [][]entities.Dependency{
+		{
+			{Id: "send:0.16.2", Scopes: []string{"prod"}, RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+			{Id: "ms:2.0.0", Scopes: []string{"prod", "@jfrog"}, RequestedBy: [][]string{{"send:0.16.2", "npm-example:0.0.3"}, {"debug:2.6.9", "send:0.16.2", "npm-example:0.0.3"}}},
+			{Id: "debug:4.1.1", Scopes: []string{"dev"}, RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+		},
+		{
+			{Id: "ms:2.0.0", Scopes: []string{"@jfrog", "prod"}, RequestedBy: [][]string{{"debug:2.6.9", "send:0.16.2", "npm-example:0.0.3"}, {"send:0.16.2", "npm-example:0.0.3"}}},
+			{Id: "debug:4.1.1", Scopes: []string{"dev"}, RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+			{Id: "send:0.16.2", Scopes: []string{"prod"}, RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+		},
+	}
+	for _, input := range inputs {
+		sortDependencies(input)
+		assert.Equal(t, expected, input)
+	}
+}
+
+func TestExtractNpmFlags(t *testing.T) {
+	testCases := []struct {
+		args     []string
+		expected []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"lodash"}, []string{}},
+		{[]string{"--production"}, []string{"--production"}},
+		{[]string{"lodash", "--prefix", "dir"}, []string{"--prefix", "dir"}},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, extractNpmFlags(testCase.args))
+	}
+}
+
+func TestGetNpmLsFlagsWithMaxDepth(t *testing.T) {
+	testCases := []struct {
+		name       string
+		maxDepth   *int
+		directOnly bool
+		npmFlags   []string
+		expected   []string
+	}{
+		{name: "full tree", npmFlags: []string{"--production"}, expected: []string{"--production"}},
+		{name: "direct dependencies", maxDepth: clientutils.Pointer(0), npmFlags: []string{}, expected: []string{"--depth=0"}},
+		{name: "limited depth", maxDepth: clientutils.Pointer(3), npmFlags: []string{"--production"}, expected: []string{"--production", "--depth=3"}},
+		{name: "direct only", directOnly: true, npmFlags: []string{}, expected: []string{"--depth=0"}},
+		{name: "direct only overrides max depth", maxDepth: clientutils.Pointer(3), directOnly: true, npmFlags: []string{}, expected: []string{"--depth=0"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			nc := (&NpmCommand{}).SetDirectOnly(testCase.directOnly)
+			if testCase.maxDepth != nil {
+				nc.SetMaxDepth(*testCase.maxDepth)
+			}
+			assert.Equal(t, testCase.expected, nc.getNpmLsFlags(testCase.npmFlags))
+		})
+	}
+}
+
+func TestSaveDependenciesDataWithTransform(t *testing.T) {
+	npmBuild, cleanUp := createTestBuild(t, "npm-dependency-transform-test")
+	defer cleanUp()
+
+	nc := &NpmCommand{npmBuild: npmBuild, moduleId: "npm-example:0.0.3"}
+	nc.SetDependencyTransform(func(dependencies []entities.Dependency) []entities.Dependency {
+		var prodDependencies []entities.Dependency
+		for _, dependency := range dependencies {
+			if !slices.Contains(dependency.Scopes, "dev") {
+				prodDependencies = append(prodDependencies, dependency)
+			}
+		}
+		return prodDependencies
+	})
+	assert.NoError(t, nc.saveDependenciesData([]entities.Dependency{
+		{Id: "send:0.16.2", Scopes: []string{"prod"}},
+		{Id: "debug:4.1.1", Scopes: []string{"dev"}},
+		{Id: "ms:2.0.0", Scopes: []string{"prod"}},
+	}))
+
+	module := getSavedModule(t, "npm-dependency-transform-test")
+	assert.Equal(t, "npm-example:0.0.3", module.Id)
+	assert.Equal(t, []entities.Dependency{{Id: "ms:2.0.0", Scopes: []string{"prod"}}, {Id: "send:0.16.2", Scopes: []string{"prod"}}}, module.Dependencies)
+}
+
+func TestCollectDependenciesChecksumsWithScopes(t *testing.T) {
+	tarballPath := filepath.Join(t.TempDir(), "send-0.16.2.tgz")
+	assert.NoError(t, os.WriteFile(tarballPath, []byte("send tarball"), 0644))
+	var locatedDependencies []string
+	locateTarball := func(name, version, integrity string) (string, error) {
+		locatedDependencies = append(locatedDependencies, name+":"+version)
+		return tarballPath, nil
+	}
+	dependencies := []npmDependency{
+		{Dependency: entities.Dependency{Id: "send:0.16.2", Scopes: []string{"prod"}}, name: "send", version: "0.16.2"},
+		{Dependency: entities.Dependency{Id: "debug:4.1.1", Scopes: []string{"dev"}}, name: "debug", version: "4.1.1"},
+	}
+
+	nc := &NpmCommand{}
+	nc.SetChecksumScopes([]string{"prod"})
+	collected, err := nc.collectDependenciesChecksums(context.Background(), dependencies, locateTarball)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"send:0.16.2"}, locatedDependencies)
+	if assert.Len(t, collected, 2) {
+		assert.Equal(t, "send:0.16.2", collected[0].Id)
+		assert.NotEmpty(t, collected[0].Sha1)
+		assert.NotEmpty(t, collected[0].Md5)
+		assert.NotEmpty(t, collected[0].Sha256)
+		// The dev dependency is kept in the build-info, without checksums.
+		assert.Equal(t, "debug:4.1.1", collected[1].Id)
+		assert.True(t, collected[1].Checksum.IsEmpty())
+	}
+}
+
+func TestCollectDependenciesChecksumsMissingTarball(t *testing.T) {
+	locateTarball := func(name, version, integrity string) (string, error) {
+		return "", errors.New("tarball not found")
+	}
+	dependencies := []npmDependency{
+		{Dependency: entities.Dependency{Id: "send:0.16.2", Scopes: []string{"prod"}}, name: "send", version: "0.16.2"},
+		{Dependency: entities.Dependency{Id: "fsevents:2.3.2", Scopes: []string{"prod"}}, name: "fsevents", version: "2.3.2", optional: true},
+	}
+	collected, err := (&NpmCommand{}).collectDependenciesChecksums(context.Background(), dependencies, locateTarball)
+	assert.NoError(t, err)
+	assert.Empty(t, collected)
+}
+
+func TestCalculateDependenciesStrictCollection(t *testing.T) {
+	// The local package has a peer dependency, which is not installed, so 'npm ls' reports it as missing.
+	projectDir, cleanUp := createTestNpmProjectWithLocalPackage(t, `{"name":"local-dep","version":"1.0.0","peerDependencies":{"missing-peer":"^1.0.0"}}`)
+	defer cleanUp()
+	npmVersion, executablePath, err := biutils.GetNpmVersionAndExecPath(log.Logger)
+	assert.NoError(t, err)
+	_, _, err = biutils.RunNpmCmd(executablePath, projectDir, []string{"install", "--legacy-peer-deps", filepath.Join("..", "local-dep", "local-dep-1.0.0.tgz")}, log.Logger)
+	assert.NoError(t, err)
+
+	nc := &NpmCommand{npmVersion: npmVersion, executablePath: executablePath, workingDirectory: projectDir, moduleId: "npm-test-project:1.0.0"}
+	// By default, the warnings are only logged.
+	dependencies, err := nc.calculateDependencies(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, dependencies, 1) {
+		assert.Equal(t, "local-dep:1.0.0", dependencies[0].Id)
+	}
+
+	_, err = nc.SetStrictCollection(true).calculateDependencies(context.Background())
+	assert.ErrorContains(t, err, "strict dependencies collection failed")
+	assert.ErrorContains(t, err, "missing-peer")
+}
+
+func TestParseVersionlessDependencies(t *testing.T) {
+	// The 'npm ls --json --all' output of a project with a missing peer dependency, which is required by two packages.
+	npmLsOutput := `{
+  "version": "1.0.0",
+  "name": "npm-test-project",
+  "dependencies": {
+    "local-dep": {
+      "version": "1.0.0",
+      "dependencies": {
+        "missing-peer": {"required": "^1.0.0", "missing": true, "problems": ["missing: missing-peer@^1.0.0, required by local-dep@1.0.0"]}
+      }
+    },
+    "other-dep": {
+      "version": "2.0.0",
+      "dependencies": {
+        "nested-dep": {
+          "version": "3.0.0",
+          "dependencies": {
+            "missing-peer": {"required": "^1.0.0", "missing": true}
+          }
+        }
+      }
+    }
+  }
+}`
+	dependencies, err := parseVersionlessDependencies([]byte(npmLsOutput), "npm-test-project:1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Dependency{{
+		Id:     "missing-peer:",
+		Scopes: []string{versionlessDependencyScope},
+		RequestedBy: [][]string{
+			{"local-dep:1.0.0", "npm-test-project:1.0.0"},
+			{"nested-dep:3.0.0", "other-dep:2.0.0", "npm-test-project:1.0.0"},
+		},
+	}}, dependencies)
+
+	_, err = parseVersionlessDependencies([]byte("not json"), "npm-test-project:1.0.0")
+	assert.Error(t, err)
+}
+
+func TestParseResolvedUrls(t *testing.T) {
+	npmLsOutput := `{
+  "version": "1.0.0",
+  "name": "npm-test-project",
+  "dependencies": {
+    "send": {
+      "version": "0.16.2",
+      "resolved": "https://acme.jfrog.io/artifactory/api/npm/npm-local/send/-/send-0.16.2.tgz",
+      "dependencies": {
+        "ms": {"version": "2.0.0", "resolved": "https://acme.jfrog.io/artifactory/api/npm/npm-remote/ms/-/ms-2.0.0.tgz"},
+        "missing-peer": {"required": "^1.0.0", "missing": true}
+      }
+    },
+    "linked": {"version": "1.0.0"}
+  }
+}`
+	resolvedUrls, err := parseResolvedUrls([]byte(npmLsOutput))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"send:0.16.2": "https://acme.jfrog.io/artifactory/api/npm/npm-local/send/-/send-0.16.2.tgz",
+		"ms:2.0.0":    "https://acme.jfrog.io/artifactory/api/npm/npm-remote/ms/-/ms-2.0.0.tgz",
+	}, resolvedUrls)
+}
+
+func TestCalculateDependenciesWithVersionlessDeps(t *testing.T) {
+	projectDir, cleanUp := createTestNpmProjectWithLocalPackage(t, `{"name":"local-dep","version":"1.0.0","peerDependencies":{"missing-peer":"^1.0.0"}}`)
+	defer cleanUp()
+	npmVersion, executablePath, err := biutils.GetNpmVersionAndExecPath(log.Logger)
+	assert.NoError(t, err)
+	_, _, err = biutils.RunNpmCmd(executablePath, projectDir, []string{"install", "--legacy-peer-deps", filepath.Join("..", "local-dep", "local-dep-1.0.0.tgz")}, log.Logger)
+	assert.NoError(t, err)
+
+	nc := (&NpmCommand{npmVersion: npmVersion, executablePath: executablePath, workingDirectory: projectDir, moduleId: "npm-test-project:1.0.0"}).SetIncludeVersionlessDeps(true)
+	dependencies, err := nc.calculateDependencies(context.Background())
+	assert.NoError(t, err)
+	sortDependencies(dependencies)
+	if assert.Len(t, dependencies, 2) {
+		assert.Equal(t, "local-dep:1.0.0", dependencies[0].Id)
+		assert.Equal(t, "missing-peer:", dependencies[1].Id)
+		assert.Equal(t, []string{versionlessDependencyScope}, dependencies[1].Scopes)
+		assert.Equal(t, [][]string{{"local-dep:1.0.0", "npm-test-project:1.0.0"}}, dependencies[1].RequestedBy)
+	}
+}
+
+func TestMarkLocalDependencies(t *testing.T) {
+	dependencies := []npmDependency{
+		{Dependency: entities.Dependency{Id: "workspace-lib:1.0.0", Scopes: []string{"prod"}}},
+		{Dependency: entities.Dependency{Id: "local-dir:1.0.0", Scopes: []string{"prod"}}},
+		{Dependency: entities.Dependency{Id: "local-tarball:1.0.0", Scopes: []string{"prod"}}},
+		{Dependency: entities.Dependency{Id: "send:0.16.2", Scopes: []string{"prod"}}},
+		{Dependency: entities.Dependency{Id: "ms:2.0.0", Scopes: []string{"prod"}}, integrity: "sha512-ms"},
+	}
+	markLocalDependencies(dependencies, map[string]string{
+		"workspace-lib:1.0.0": "link:../packages/workspace-lib",
+		"local-dir:1.0.0":     "file:../local-dir",
+		"local-tarball:1.0.0": "file:../local-tarball-1.0.0.tgz",
+		"send:0.16.2":         "https://acme.jfrog.io/artifactory/api/npm/npm-remote/send/-/send-0.16.2.tgz",
+	})
+	var localDependencies []string
+	for _, dep := range dependencies {
+		if dep.local {
+			localDependencies = append(localDependencies, dep.Id)
+			assert.Equal(t, []string{"prod", localDependencyScope}, dep.Scopes)
+		}
+	}
+	assert.Equal(t, []string{"workspace-lib:1.0.0", "local-dir:1.0.0"}, localDependencies)
+}
+
+func TestCalculateDependenciesWithLinkedDependency(t *testing.T) {
+	projectDir, cleanUp := createTestNpmProjectWithLocalPackage(t, `{"name":"local-dep","version":"1.0.0"}`)
+	defer cleanUp()
+	npmVersion, executablePath, err := biutils.GetNpmVersionAndExecPath(log.Logger)
+	assert.NoError(t, err)
+	// A local directory, which npm links into node_modules.
+	_, _, err = biutils.RunNpmCmd(executablePath, projectDir, []string{"install", "--offline", filepath.Join("..", "local-dep")}, log.Logger)
+	assert.NoError(t, err)
+
+	nc := &NpmCommand{npmVersion: npmVersion, executablePath: executablePath, workingDirectory: projectDir, moduleId: "npm-test-project:1.0.0", strictCollection: true}
+	dependencies, err := nc.calculateDependencies(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, dependencies, 1) {
+		assert.Equal(t, "local-dep:1.0.0", dependencies[0].Id)
+		assert.Contains(t, dependencies[0].Scopes, localDependencyScope)
+		assert.True(t, dependencies[0].IsEmpty())
+	}
+}
+
+func TestCalculateDependenciesWithCollectionStrategy(t *testing.T) {
+	testCases := []struct {
+		name              string
+		strategy          CollectionStrategy
+		removeNodeModules bool
+		removePackageLock bool
+		expectedErr       string
+	}{
+		{name: "default", strategy: ""},
+		{name: "auto", strategy: AutoCollectionStrategy},
+		{name: "auto without node_modules", strategy: AutoCollectionStrategy, removeNodeModules: true},
+		{name: "npm ls", strategy: NpmLsCollectionStrategy},
+		{name: "npm ls without node_modules", strategy: NpmLsCollectionStrategy, removeNodeModules: true, expectedErr: "requires the installed node_modules"},
+		{name: "lockfile", strategy: LockfileCollectionStrategy},
+		{name: "lockfile without node_modules", strategy: LockfileCollectionStrategy, removeNodeModules: true},
+		{name: "lockfile without package-lock.json", strategy: LockfileCollectionStrategy, removePackageLock: true, expectedErr: "requires package-lock.json"},
+		{name: "node_modules scan", strategy: NodeModulesScanCollectionStrategy},
+		{name: "node_modules scan without package-lock.json", strategy: NodeModulesScanCollectionStrategy, removePackageLock: true},
+		{name: "node_modules scan without node_modules", strategy: NodeModulesScanCollectionStrategy, removeNodeModules: true, expectedErr: "requires the installed node_modules"},
+		{name: "unsupported", strategy: "cache", expectedErr: "unsupported npm collection strategy"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			projectDir, cleanUp := createTestNpmProjectWithLocalPackage(t, `{"name":"local-dep","version":"1.0.0"}`)
+			defer cleanUp()
+			npmVersion, executablePath, err := biutils.GetNpmVersionAndExecPath(log.Logger)
+			assert.NoError(t, err)
+			_, _, err = biutils.RunNpmCmd(executablePath, projectDir, []string{"install", "--offline", filepath.Join("..", "local-dep", "local-dep-1.0.0.tgz")}, log.Logger)
+			assert.NoError(t, err)
+			if testCase.removeNodeModules {
+				assert.NoError(t, os.RemoveAll(filepath.Join(projectDir, "node_modules")))
+			}
+			if testCase.removePackageLock {
+				assert.NoError(t, os.Remove(filepath.Join(projectDir, "package-lock.json")))
+			}
+
+			nc := (&NpmCommand{npmVersion: npmVersion, executablePath: executablePath, workingDirectory: projectDir, moduleId: "npm-test-project:1.0.0"}).
+				SetCollectionStrategy(testCase.strategy)
+			dependencies, err := nc.calculateDependencies(context.Background())
+			if testCase.expectedErr != "" {
+				assert.ErrorContains(t, err, testCase.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			if assert.Len(t, dependencies, 1) {
+				assert.Equal(t, "local-dep:1.0.0", dependencies[0].Id)
+				assert.NotEmpty(t, dependencies[0].Sha1)
+				assert.Equal(t, [][]string{{"npm-test-project:1.0.0"}}, dependencies[0].RequestedBy)
+			}
+		})
+	}
+}
+
+func TestCalculateDependenciesWithMaxDependencies(t *testing.T) {
+	projectDir, cleanUp := createTestNpmProjectWithLocalPackage(t, `{"name":"local-dep","version":"1.0.0"}`)
+	defer cleanUp()
+	npmVersion, executablePath, err := biutils.GetNpmVersionAndExecPath(log.Logger)
+	assert.NoError(t, err)
+	// A second local package, so that the project has two dependencies.
+	secondPackageDir := filepath.Join(filepath.Dir(projectDir), "local-dep-b")
+	assert.NoError(t, os.MkdirAll(secondPackageDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(secondPackageDir, "package.json"), []byte(`{"name":"local-dep-b","version":"2.0.0"}`), 0644))
+	_, _, err = biutils.RunNpmCmd(executablePath, secondPackageDir, []string{"pack"}, log.Logger)
+	assert.NoError(t, err)
+	_, _, err = biutils.RunNpmCmd(executablePath, projectDir, []string{"install", "--offline",
+		filepath.Join("..", "local-dep", "local-dep-1.0.0.tgz"), filepath.Join("..", "local-dep-b", "local-dep-b-2.0.0.tgz")}, log.Logger)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name            string
+		maxDependencies int
+		truncate        bool
+		expectedIds     []string
+		expectedErr     bool
+	}{
+		{name: "no limit", expectedIds: []string{"local-dep-b:2.0.0", "local-dep:1.0.0"}},
+		{name: "within the limit", maxDependencies: 2, expectedIds: []string{"local-dep-b:2.0.0", "local-dep:1.0.0"}},
+		{name: "exceeding the limit", maxDependencies: 1, expectedErr: true},
+		{name: "exceeding the limit with truncating", maxDependencies: 1, truncate: true, expectedIds: []string{"local-dep-b:2.0.0"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			nc := (&NpmCommand{npmVersion: npmVersion, executablePath: executablePath, workingDirectory: projectDir, moduleId: "npm-test-project:1.0.0"}).
+				SetMaxDependencies(testCase.maxDependencies).SetTruncateDependencies(testCase.truncate)
+			dependencies, err := nc.calculateDependencies(context.Background())
+			if testCase.expectedErr {
+				assert.ErrorContains(t, err, "the project has 2 dependencies, which exceeds the maximum of 1")
+				return
+			}
+			assert.NoError(t, err)
+			sortDependencies(dependencies)
+			var ids []string
+			for _, dependency := range dependencies {
+				ids = append(ids, dependency.Id)
+			}
+			assert.Equal(t, testCase.expectedIds, ids)
+		})
+	}
+}
+
+func TestRunNpmLsRetries(t *testing.T) {
+	previousRunNpmCmd, previousWait := runNpmCmd, npmLsRetryInitialWait
+	defer func() {
+		runNpmCmd, npmLsRetryInitialWait = previousRunNpmCmd, previousWait
+	}()
+	npmLsRetryInitialWait = time.Millisecond
+	testCases := []struct {
+		name             string
+		failures         int
+		expectedError    bool
+		expectedAttempts int
+	}{
+		{name: "success", failures: 0, expectedAttempts: 1},
+		{name: "fails twice then succeeds", failures: 2, expectedAttempts: 3},
+		{name: "always fails", failures: 10, expectedError: true, expectedAttempts: npmLsMaxRetries + 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			attempts := 0
+			runNpmCmd = func(_, _ string, npmArgs []string, _ buildInfoUtils.Log) ([]byte, []byte, error) {
+				attempts++
+				assert.Equal(t, "ls", npmArgs[0])
+				if attempts <= testCase.failures {
+					return nil, []byte("npm error code ENOENT"), errors.New("npm error code ENOENT")
+				}
+				return []byte(`{"name":"npm-test-project"}`), nil, nil
+			}
+			output, err := (&NpmCommand{}).runNpmLs(nil)
+			assert.Equal(t, testCase.expectedAttempts, attempts)
+			if testCase.expectedError {
+				// The error of the last attempt is surfaced.
+				assert.ErrorContains(t, err, "ENOENT")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, `{"name":"npm-test-project"}`, string(output))
+		})
+	}
+}
+
+func TestParseNpmLsProblems(t *testing.T) {
+	npmLsOutput, err := os.ReadFile(filepath.Join("..", "testdata", "npm", "npm-ls-problems.json"))
+	assert.NoError(t, err)
+	problems, err := parseNpmLsProblems(npmLsOutput)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"missing: ms@^2.1.3, required by debug@4.3.4",
+		"invalid: send@0.16.2 /project/node_modules/send",
+		"extraneous: left-pad@1.3.0 /project/node_modules/left-pad",
+	}, problems)
+
+	problems, err = parseNpmLsProblems([]byte(`{"name":"npm-example","version":"1.0.0"}`))
+	assert.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestReportNpmLsProblems(t *testing.T) {
+	npmLsOutput, err := os.ReadFile(filepath.Join("..", "testdata", "npm", "npm-ls-problems.json"))
+	assert.NoError(t, err)
+	previousRunNpmCmd := runNpmCmd
+	defer func() {
+		runNpmCmd = previousRunNpmCmd
+	}()
+	runNpmCmd = func(_, _ string, npmArgs []string, _ buildInfoUtils.Log) ([]byte, []byte, error) {
+		assert.Equal(t, []string{"ls", "--json", "--all", "--package-lock-only"}, npmArgs)
+		// 'npm ls' fails when the tree has problems, but still prints it.
+		return npmLsOutput, []byte("npm error missing: ms@^2.1.3, required by debug@4.3.4"), errors.New("exit status 1")
+	}
+
+	// By default, the problems are logged as warnings.
+	_, buffer, previousLog := tests.RedirectLogOutputToBuffer()
+	defer log.SetLogger(previousLog)
+	assert.NoError(t, (&NpmCommand{}).reportNpmLsProblems([]string{"--package-lock-only"}))
+	assert.Contains(t, buffer.String(), "'npm ls' reported the following problems")
+	assert.Contains(t, buffer.String(), "invalid: send@0.16.2 /project/node_modules/send")
+
+	// In strict collection mode, the problems fail the collection.
+	err = (&NpmCommand{}).SetStrictCollection(true).reportNpmLsProblems([]string{"--package-lock-only"})
+	assert.ErrorContains(t, err, "'npm ls' reported the following problems:\nmissing: ms@^2.1.3, required by debug@4.3.4")
+}
+
+func TestCalculateDependenciesGlobal(t *testing.T) {
+	projectDir, cleanUp := createTestNpmProjectWithLocalPackage(t, `{"name":"local-dep","version":"1.0.0"}`)
+	defer cleanUp()
+	npmVersion, executablePath, err := biutils.GetNpmVersionAndExecPath(log.Logger)
+	assert.NoError(t, err)
+	otherPackageDir := filepath.Join(filepath.Dir(projectDir), "other-dep")
+	assert.NoError(t, os.MkdirAll(otherPackageDir, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(otherPackageDir, "package.json"), []byte(`{"name":"other-dep","version":"2.0.0"}`), 0644))
+	_, _, err = biutils.RunNpmCmd(executablePath, otherPackageDir, []string{"pack"}, log.Logger)
+	assert.NoError(t, err)
+	// Install the packages globally in a temporary global prefix.
+	t.Setenv("npm_config_prefix", filepath.Join(filepath.Dir(projectDir), "global"))
+	_, _, err = biutils.RunNpmCmd(executablePath, projectDir, []string{"install", "-g",
+		filepath.Join("..", "local-dep", "local-dep-1.0.0.tgz"), filepath.Join("..", "other-dep", "other-dep-2.0.0.tgz")}, log.Logger)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		npmArgs     []string
+		expectedIds []string
+	}{
+		{name: "installed package", npmArgs: []string{"local-dep@1.0.0", "-g"}, expectedIds: []string{"local-dep:1.0.0"}},
+		{name: "all global packages", npmArgs: []string{"-g"}, expectedIds: []string{"local-dep:1.0.0", "other-dep:2.0.0"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// Packages installed from local tarballs can't be found in the npm cache by their names, so no checksums are calculated.
+			nc := (&NpmCommand{npmVersion: npmVersion, executablePath: executablePath, workingDirectory: projectDir, moduleId: globalModuleId}).SetGlobal(true).SetChecksumScopes([]string{"dev"})
+			nc.npmArgs = testCase.npmArgs
+			dependencies, err := nc.calculateDependencies(context.Background())
+			assert.NoError(t, err)
+			var ids []string
+			for _, dep := range dependencies {
+				assert.Equal(t, [][]string{{globalModuleId}}, dep.RequestedBy)
+				ids = append(ids, dep.Id)
+			}
+			slices.Sort(ids)
+			assert.Equal(t, testCase.expectedIds, ids)
+		})
+	}
+}
+
+func TestGetGlobalNpmLsFlags(t *testing.T) {
+	assert.Equal(t, []string{"--global"}, getGlobalNpmLsFlags([]string{"typescript", "-g"}))
+	assert.Equal(t, []string{"--global", "--prefix=/opt/npm"}, getGlobalNpmLsFlags([]string{"--location=global", "@jfrog/pkg@1.0.0", "--prefix=/opt/npm"}))
+}
+
+func TestCollectDependenciesChecksumsErrors(t *testing.T) {
+	tarballsDir := t.TempDir()
+	validTarball := filepath.Join(tarballsDir, "valid-1.0.0.tgz")
+	assert.NoError(t, os.WriteFile(validTarball, []byte("content"), 0644))
+	// The tarballs of the broken dependencies are located, but can't be read.
+	locateTarball := func(name, version, integrity string) (string, error) {
+		if name == "valid" {
+			return validTarball, nil
+		}
+		return filepath.Join(tarballsDir, name+"-"+version+".tgz"), nil
+	}
+	dependencies := []npmDependency{
+		{Dependency: entities.Dependency{Id: "broken-a:1.0.0"}, name: "broken-a", version: "1.0.0"},
+		{Dependency: entities.Dependency{Id: "valid:1.0.0"}, name: "valid", version: "1.0.0"},
+		{Dependency: entities.Dependency{Id: "broken-b:2.0.0"}, name: "broken-b", version: "2.0.0"},
+	}
+	_, err := (&NpmCommand{}).collectDependenciesChecksums(context.Background(), dependencies, locateTarball)
+	assert.ErrorContains(t, err, "broken-a:1.0.0")
+	assert.ErrorContains(t, err, "broken-b:2.0.0")
+	assert.NotContains(t, err.Error(), "valid:1.0.0")
+	var joinedErrors interface{ Unwrap() []error }
+	if assert.True(t, errors.As(err, &joinedErrors)) {
+		assert.Len(t, joinedErrors.Unwrap(), 2)
+	}
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestCollectDependenciesChecksumsStrictCollection(t *testing.T) {
+	locateTarball := func(name, version, integrity string) (string, error) {
+		return "", errors.New("tarball not found")
+	}
+	dependencies := []npmDependency{{Dependency: entities.Dependency{Id: "send:0.16.2", Scopes: []string{"prod"}}, name: "send", version: "0.16.2"}}
+	_, err := (&NpmCommand{strictCollection: true}).collectDependenciesChecksums(context.Background(), dependencies, locateTarball)
+	assert.ErrorContains(t, err, "send:0.16.2")
+}
+
+func TestSaveDependenciesDataWithBuildTimestamp(t *testing.T) {
+	buildName := "npm-build-timestamp-test"
+	// A previous command of the build saved the general details of the build with its own start time.
+	_, cleanUp := createTestBuild(t, buildName)
+	defer cleanUp()
+
+	projectDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "package.json"), []byte(`{"name":"npm-example","version":"0.0.3"}`), 0644))
+	buildTimestamp := time.Date(2024, time.March, 1, 12, 30, 0, 0, time.UTC)
+	nc := &NpmCommand{
+		cmdName:          "install",
+		collectBuildInfo: true,
+		workingDirectory: projectDir,
+		CommonArgs:       CommonArgs{buildConfiguration: buildUtils.NewBuildConfiguration(buildName, "1", "", "")},
+	}
+	assert.NoError(t, nc.SetBuildTimestamp(buildTimestamp).prepareBuildInfoModule())
+	assert.NoError(t, nc.saveDependenciesData([]entities.Dependency{{Id: "send:0.16.2", Scopes: []string{"prod"}}}))
+
+	// The timestamp is read from the general details of the build when the build-info is aggregated for publishing.
+	buildInfo, err := nc.npmBuild.ToBuildInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-03-01T12:30:00.000+0000", buildInfo.Started)
+	if assert.Len(t, buildInfo.Modules, 1) {
+		assert.Equal(t, []entities.Dependency{{Id: "send:0.16.2", Scopes: []string{"prod"}}}, buildInfo.Modules[0].Dependencies)
+	}
+}
+
+func TestSaveDependenciesDataWithModuleProperties(t *testing.T) {
+	npmBuild, cleanUp := createTestBuild(t, "npm-module-properties-test")
+	defer cleanUp()
+
+	nc := (&NpmCommand{npmBuild: npmBuild, moduleId: "npm-example:0.0.3"}).SetModuleProperties(map[string]string{"git.commit": "abc123", "env": "staging"})
+	assert.NoError(t, nc.validateModuleProperties())
+	nc.setModuleProperty(licenseModulePropertyPrefix+"send:0.16.2", "MIT")
+	assert.NoError(t, nc.saveDependenciesData([]entities.Dependency{{Id: "send:0.16.2", Scopes: []string{"prod"}}}))
+	module := getSavedModule(t, "npm-module-properties-test")
+	assert.Equal(t, map[string]interface{}{"git.commit": "abc123", "env": "staging", licenseModulePropertyPrefix + "send:0.16.2": "MIT"}, module.Properties)
+
+	// Empty keys are rejected.
+	nc.SetModuleProperties(map[string]string{" ": "value"})
+	assert.Error(t, nc.validateModuleProperties())
+}
+
+func TestSaveDependenciesDataWithBuildInfoWriter(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		skipBuildInfoPartial bool
+	}{
+		{name: "with partial"},
+		{name: "writer only", skipBuildInfoPartial: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			buildName := "npm-build-info-writer-test"
+			npmBuild, cleanUp := createTestBuild(t, buildName)
+			defer cleanUp()
+
+			var buildInfoWriter bytes.Buffer
+			nc := (&NpmCommand{npmBuild: npmBuild, moduleId: "npm-example:0.0.3"}).
+				SetBuildInfoWriter(&buildInfoWriter).SetSkipBuildInfoPartial(testCase.skipBuildInfoPartial)
+			dependencies := []entities.Dependency{{Id: "send:0.16.2", Scopes: []string{"prod"}, Checksum: entities.Checksum{Sha1: "send-sha1"}}}
+			assert.NoError(t, nc.saveDependenciesData(dependencies))
+
+			var writtenBuildInfo entities.BuildInfo
+			assert.NoError(t, json.Unmarshal(buildInfoWriter.Bytes(), &writtenBuildInfo))
+			if assert.Len(t, writtenBuildInfo.Modules, 1) {
+				assert.Equal(t, "npm-example:0.0.3", writtenBuildInfo.Modules[0].Id)
+				assert.Equal(t, entities.Npm, writtenBuildInfo.Modules[0].Type)
+				assert.Equal(t, dependencies, writtenBuildInfo.Modules[0].Dependencies)
+			}
+
+			buildsInfo, err := buildUtils.GetGeneratedBuildsInfo(buildName, "1", "")
+			assert.NoError(t, err)
+			if testCase.skipBuildInfoPartial {
+				assert.Empty(t, buildsInfo)
+				assert.False(t, nc.result.BuildInfoSaved)
+			} else {
+				assert.Len(t, buildsInfo, 1)
+				assert.True(t, nc.result.BuildInfoSaved)
+			}
+		})
+	}
+}
+
+func TestSaveDependenciesDataWithLockfile(t *testing.T) {
+	npmBuild, cleanUp := createTestBuild(t, "npm-lockfile-test")
+	defer cleanUp()
+	projectDir := t.TempDir()
+	lockfileContent := []byte(`{"name":"npm-example","version":"0.0.3","lockfileVersion":3,"packages":{}}`)
+	assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "package-lock.json"), lockfileContent, 0644))
+
+	nc := (&NpmCommand{npmBuild: npmBuild, moduleId: "npm-example:0.0.3", workingDirectory: projectDir}).SetAttachLockfile(true)
+	assert.NoError(t, nc.saveDependenciesData([]entities.Dependency{{Id: "send:0.16.2", Scopes: []string{"prod"}}}))
+	module := getSavedModule(t, "npm-lockfile-test")
+	if assert.Len(t, module.Artifacts, 1) {
+		assert.Equal(t, "package-lock.json", module.Artifacts[0].Name)
+		assert.Equal(t, "json", module.Artifacts[0].Type)
+		assert.Equal(t, fmt.Sprintf("%x", sha1.Sum(lockfileContent)), module.Artifacts[0].Sha1)
+		assert.NotEmpty(t, module.Artifacts[0].Sha256)
+	}
+}
+
+func TestSaveDependenciesDataWithoutLockfile(t *testing.T) {
+	npmBuild, cleanUp := createTestBuild(t, "npm-no-lockfile-test")
+	defer cleanUp()
+
+	nc := (&NpmCommand{npmBuild: npmBuild, moduleId: "npm-example:0.0.3", workingDirectory: t.TempDir()}).SetAttachLockfile(true)
+	assert.NoError(t, nc.saveDependenciesData([]entities.Dependency{{Id: "send:0.16.2", Scopes: []string{"prod"}}}))
+	assert.Empty(t, getSavedModule(t, "npm-no-lockfile-test").Artifacts)
+}
+
+func TestCollectDependenciesChecksumsInterrupted(t *testing.T) {
+	npmBuild, cleanUp := createTestBuild(t, "npm-interrupted-collection-test")
+	defer cleanUp()
+	tarballPath := filepath.Join(t.TempDir(), "package.tgz")
+	assert.NoError(t, os.WriteFile(tarballPath, []byte("tarball"), 0644))
+	// Simulate an interrupt after the first dependency is collected.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	locateTarball := func(name, version, integrity string) (string, error) {
+		cancel()
+		return tarballPath, nil
+	}
+	dependencies := []npmDependency{
+		{Dependency: entities.Dependency{Id: "send:0.16.2", Scopes: []string{"prod"}}, name: "send", version: "0.16.2"},
+		{Dependency: entities.Dependency{Id: "debug:4.1.1", Scopes: []string{"prod"}}, name: "debug", version: "4.1.1"},
+	}
+
+	nc := &NpmCommand{npmBuild: npmBuild, moduleId: "npm-example:0.0.3"}
+	collected, err := nc.collectDependenciesChecksums(ctx, dependencies, locateTarball)
+	assert.ErrorIs(t, err, errCollectionInterrupted)
+	if assert.Len(t, collected, 1) {
+		assert.Equal(t, "send:0.16.2", collected[0].Id)
+	}
+
+	assert.NoError(t, nc.saveIncompleteDependenciesData(collected))
+	module := getSavedModule(t, "npm-interrupted-collection-test")
+	assert.Equal(t, map[string]interface{}{incompleteModuleProperty: "true"}, module.Properties)
+	if assert.Len(t, module.Dependencies, 1) {
+		assert.Equal(t, "send:0.16.2", module.Dependencies[0].Id)
+		assert.NotEmpty(t, module.Dependencies[0].Sha1)
+	}
+}
+
+func TestSaveDependenciesDataWithMergeModule(t *testing.T) {
+	buildName := "npm-merge-module-test"
+	npmBuild, cleanUp := createTestBuild(t, buildName)
+	defer cleanUp()
+	buildConfiguration := buildUtils.NewBuildConfiguration(buildName, "1", "", "")
+
+	// A module with a different ID, which isn't merged.
+	otherNc := &NpmCommand{npmBuild: npmBuild, moduleId: "other:1.0.0"}
+	assert.NoError(t, otherNc.saveDependenciesData([]entities.Dependency{{Id: "ms:2.0.0", Scopes: []string{"prod"}}}))
+
+	// The first run, in one directory.
+	firstNc := (&NpmCommand{npmBuild: npmBuild, moduleId: "merged:1.0.0", CommonArgs: CommonArgs{buildConfiguration: buildConfiguration}}).SetMergeModule(true)
+	assert.NoError(t, firstNc.saveDependenciesData([]entities.Dependency{
+		{Id: "send:0.16.2", Scopes: []string{"prod"}, RequestedBy: [][]string{{"merged:1.0.0"}}, Checksum: entities.Checksum{Sha1: "send-sha1"}},
+		{Id: "debug:4.1.1", Scopes: []string{"dev"}, RequestedBy: [][]string{{"merged:1.0.0"}}},
+	}))
+	// The second run, in another directory, shares a dependency with the first.
+	secondNc := (&NpmCommand{npmBuild: npmBuild, moduleId: "merged:1.0.0", CommonArgs: CommonArgs{buildConfiguration: buildConfiguration}}).SetMergeModule(true)
+	assert.NoError(t, secondNc.saveDependenciesData([]entities.Dependency{
+		{Id: "debug:4.1.1", Scopes: []string{"prod"}, RequestedBy: [][]string{{"send:0.16.2", "merged:1.0.0"}}, Checksum: entities.Checksum{Sha1: "debug-sha1"}},
+		{Id: "ms:2.0.0", Scopes: []string{"prod"}, RequestedBy: [][]string{{"debug:4.1.1", "merged:1.0.0"}}},
+	}))
+
+	buildsInfo, err := buildUtils.GetGeneratedBuildsInfo(buildName, "1", "")
+	assert.NoError(t, err)
+	var mergedModules []entities.Module
+	for _, buildInfo := range buildsInfo {
+		for _, module := range buildInfo.Modules {
+			if module.Id == "merged:1.0.0" {
+				mergedModules = append(mergedModules, module)
+			}
+		}
+	}
+	assert.Len(t, buildsInfo, 2)
+	if assert.Len(t, mergedModules, 1) {
+		assert.Equal(t, []entities.Dependency{
+			{Id: "debug:4.1.1", Scopes: []string{"dev", "prod"}, RequestedBy: [][]string{{"merged:1.0.0"}, {"send:0.16.2", "merged:1.0.0"}}, Checksum: entities.Checksum{Sha1: "debug-sha1"}},
+			{Id: "ms:2.0.0", Scopes: []string{"prod"}, RequestedBy: [][]string{{"debug:4.1.1", "merged:1.0.0"}}},
+			{Id: "send:0.16.2", Scopes: []string{"prod"}, RequestedBy: [][]string{{"merged:1.0.0"}}, Checksum: entities.Checksum{Sha1: "send-sha1"}},
+		}, mergedModules[0].Dependencies)
+	}
+}
+
+// Creates a build with the given name and build number 1, to save build-info in.
+func createTestBuild(t *testing.T, buildName string) (npmBuild *build.Build, cleanUp func()) {
+	assert.NoError(t, buildUtils.RemoveBuildDir(buildName, "1", ""))
+	npmBuild, err := buildUtils.CreateBuildInfoService().GetOrCreateBuildWithProject(buildName, "1", "")
+	assert.NoError(t, err)
+	return npmBuild, func() {
+		assert.NoError(t, buildUtils.RemoveBuildDir(buildName, "1", ""))
+	}
+}
+
+// Returns the single module saved in the build-info of the given build name and build number 1.
+func getSavedModule(t *testing.T, buildName string) entities.Module {
+	buildsInfo, err := buildUtils.GetGeneratedBuildsInfo(buildName, "1", "")
+	assert.NoError(t, err)
+	if !assert.Len(t, buildsInfo, 1) || !assert.Len(t, buildsInfo[0].Modules, 1) {
+		t.FailNow()
+	}
+	return buildsInfo[0].Modules[0]
+}
@@ -0,0 +1,92 @@
+package npm
+
+import (
+	"sort"
+
+	"github.com/jfrog/build-info-go/entities"
+)
+
+// DependencyDiff describes the changes between two sets of npm dependencies, such as the dependencies of two builds.
+type DependencyDiff struct {
+	// The dependencies of packages which only exist in the second set.
+	Added []entities.Dependency
+	// The dependencies of packages which only exist in the first set.
+	Removed []entities.Dependency
+	// The packages whose version was changed.
+	VersionChanged []DependencyVersionChange
+}
+
+// DependencyVersionChange describes a package whose version differs between the two sets of dependencies.
+type DependencyVersionChange struct {
+	Name string
+	// The package's dependency in the first set.
+	Previous entities.Dependency
+	// The package's dependency in the second set.
+	Current entities.Dependency
+}
+
+// IsEmpty returns true if the two sets of dependencies have the same packages, in the same versions.
+func (diff DependencyDiff) IsEmpty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.VersionChanged) == 0
+}
+
+// DiffDependencies compares two sets of npm dependencies, whose IDs are in the form of name:version, and returns the changes from a to b.
+// A package which is installed in a single version in each set, and whose version was changed, is reported as a version change.
+// Since npm may install several versions of the same package, the changed versions of a package with several versions are reported as added and removed.
+// The results are sorted by the dependencies' IDs.
+func DiffDependencies(a, b []entities.Dependency) DependencyDiff {
+	previousVersions := groupDependenciesByName(a)
+	currentVersions := groupDependenciesByName(b)
+	var diff DependencyDiff
+	for name, previous := range previousVersions {
+		current := currentVersions[name]
+		removed := subtractDependencyVersions(previous, current)
+		added := subtractDependencyVersions(current, previous)
+		if len(previous) == 1 && len(current) == 1 && len(removed) == 1 && len(added) == 1 {
+			diff.VersionChanged = append(diff.VersionChanged, DependencyVersionChange{Name: name, Previous: removed[0], Current: added[0]})
+			continue
+		}
+		diff.Removed = append(diff.Removed, removed...)
+		diff.Added = append(diff.Added, added...)
+	}
+	for name, current := range currentVersions {
+		if _, exists := previousVersions[name]; !exists {
+			diff.Added = append(diff.Added, subtractDependencyVersions(current, nil)...)
+		}
+	}
+	sortDependenciesById(diff.Added)
+	sortDependenciesById(diff.Removed)
+	sort.Slice(diff.VersionChanged, func(i, j int) bool { return diff.VersionChanged[i].Name < diff.VersionChanged[j].Name })
+	return diff
+}
+
+// Groups the dependencies by the package names, and the versions of each package by the versions.
+// A dependency which appears more than once in the set is kept once.
+func groupDependenciesByName(dependencies []entities.Dependency) map[string]map[string]entities.Dependency {
+	grouped := make(map[string]map[string]entities.Dependency)
+	for _, dependency := range dependencies {
+		name, version := splitNpmDependencyId(dependency.Id)
+		if grouped[name] == nil {
+			grouped[name] = make(map[string]entities.Dependency)
+		}
+		if _, exists := grouped[name][version]; !exists {
+			grouped[name][version] = dependency
+		}
+	}
+	return grouped
+}
+
+// Returns the dependencies of the versions in 'versions' which aren't in 'other'.
+func subtractDependencyVersions(versions, other map[string]entities.Dependency) (result []entities.Dependency) {
+	for version, dependency := range versions {
+		if _, exists := other[version]; !exists {
+			result = append(result, dependency)
+		}
+	}
+	return
+}
+
+// Unlike sortDependencies, the scopes and requestedBy paths aren't sorted, since they're shared with the compared sets.
+func sortDependenciesById(dependencies []entities.Dependency) {
+	sort.Slice(dependencies, func(i, j int) bool { return dependencies[i].Id < dependencies[j].Id })
+}
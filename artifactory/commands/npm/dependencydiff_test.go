@@ -0,0 +1,69 @@
+package npm
+
+import (
+	"testing"
+
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDependencies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		a        []entities.Dependency
+		b        []entities.Dependency
+		expected DependencyDiff
+	}{
+		{name: "empty sets"},
+		{
+			name: "identical sets",
+			a:    []entities.Dependency{{Id: "debug:4.1.1"}, {Id: "@jfrog/package:1.0.0"}},
+			b:    []entities.Dependency{{Id: "@jfrog/package:1.0.0"}, {Id: "debug:4.1.1"}},
+		},
+		{
+			name: "added and removed",
+			a:    []entities.Dependency{{Id: "debug:4.1.1"}, {Id: "ms:2.1.2"}},
+			b:    []entities.Dependency{{Id: "debug:4.1.1"}, {Id: "@jfrog/package:1.0.0"}, {Id: "lodash:4.17.21"}},
+			expected: DependencyDiff{
+				Added:   []entities.Dependency{{Id: "@jfrog/package:1.0.0"}, {Id: "lodash:4.17.21"}},
+				Removed: []entities.Dependency{{Id: "ms:2.1.2"}},
+			},
+		},
+		{
+			name: "version changed",
+			a:    []entities.Dependency{{Id: "@jfrog/package:1.0.0", Scopes: []string{"prod"}}, {Id: "debug:4.1.1"}},
+			b:    []entities.Dependency{{Id: "@jfrog/package:1.1.0", Scopes: []string{"prod"}}, {Id: "debug:4.1.1"}},
+			expected: DependencyDiff{
+				VersionChanged: []DependencyVersionChange{{
+					Name:     "@jfrog/package",
+					Previous: entities.Dependency{Id: "@jfrog/package:1.0.0", Scopes: []string{"prod"}},
+					Current:  entities.Dependency{Id: "@jfrog/package:1.1.0", Scopes: []string{"prod"}},
+				}},
+			},
+		},
+		{
+			name: "several versions of a package",
+			a:    []entities.Dependency{{Id: "ms:2.0.0"}, {Id: "ms:2.1.2"}},
+			b:    []entities.Dependency{{Id: "ms:2.1.2"}, {Id: "ms:2.1.3"}},
+			expected: DependencyDiff{
+				Added:   []entities.Dependency{{Id: "ms:2.1.3"}},
+				Removed: []entities.Dependency{{Id: "ms:2.0.0"}},
+			},
+		},
+		{
+			name: "duplicate dependencies",
+			a:    []entities.Dependency{{Id: "ms:2.0.0"}, {Id: "ms:2.0.0"}},
+			b:    []entities.Dependency{{Id: "ms:2.1.2"}},
+			expected: DependencyDiff{
+				VersionChanged: []DependencyVersionChange{{Name: "ms", Previous: entities.Dependency{Id: "ms:2.0.0"}, Current: entities.Dependency{Id: "ms:2.1.2"}}},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			diff := DiffDependencies(testCase.a, testCase.b)
+			assert.Equal(t, testCase.expected, diff)
+			assert.Equal(t, testCase.expected.IsEmpty(), diff.IsEmpty())
+		})
+	}
+}
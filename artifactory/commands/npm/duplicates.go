@@ -0,0 +1,66 @@
+package npm
+
+import (
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/jfrog/gofrog/version"
+	"golang.org/x/exp/slices"
+)
+
+// DuplicateVersionPolicy determines how a package installed in several versions is represented in the build-info.
+type DuplicateVersionPolicy string
+
+const (
+	// The default. Every installed version of the package is saved, which reflects the installed tree.
+	AllVersionsPolicy DuplicateVersionPolicy = "all"
+	// Only the highest version of the package is saved.
+	HighestVersionPolicy DuplicateVersionPolicy = "highest"
+	// Only the version which is the closest to the project in the dependencies tree is saved. This is usually the version which npm
+	// installs at the top of node_modules. If several versions are equally close, the one with the lowest ID is saved.
+	FirstVersionPolicy DuplicateVersionPolicy = "first"
+)
+
+var duplicateVersionPolicies = []DuplicateVersionPolicy{AllVersionsPolicy, HighestVersionPolicy, FirstVersionPolicy}
+
+// Keeps a single version of each package, according to the policy. The dependencies of the other versions are dropped as they are,
+// so the scopes and requestedBy paths of the saved version aren't changed. The order of the dependencies is kept.
+func applyDuplicateVersionPolicy(dependencies []entities.Dependency, policy DuplicateVersionPolicy) []entities.Dependency {
+	if policy == "" || policy == AllVersionsPolicy {
+		return dependencies
+	}
+	// The index of the kept dependency of each package, by the package names.
+	keptByName := make(map[string]int)
+	for i, dependency := range dependencies {
+		name, _ := splitNpmDependencyId(dependency.Id)
+		kept, exists := keptByName[name]
+		if !exists || isPreferredVersion(dependency, dependencies[kept], policy) {
+			keptByName[name] = i
+		}
+	}
+	var result []entities.Dependency
+	for i, dependency := range dependencies {
+		name, _ := splitNpmDependencyId(dependency.Id)
+		if keptByName[name] == i {
+			result = append(result, dependency)
+		}
+	}
+	return result
+}
+
+// Returns true if the dependency should be kept rather than the other version of the same package, according to the policy.
+func isPreferredVersion(dependency, other entities.Dependency, policy DuplicateVersionPolicy) bool {
+	_, dependencyVersion := splitNpmDependencyId(dependency.Id)
+	_, otherVersion := splitNpmDependencyId(other.Id)
+	if policy == HighestVersionPolicy {
+		return version.NewVersion(otherVersion).Compare(dependencyVersion) > 0
+	}
+	dependencyDepth, otherDepth := getDependencyDepth(dependency), getDependencyDepth(other)
+	return dependencyDepth < otherDepth || (dependencyDepth == otherDepth && dependency.Id < other.Id)
+}
+
+// Returns the length of the shortest requestedBy path of the dependency, where the direct dependencies' depth is 1.
+func getDependencyDepth(dependency entities.Dependency) int {
+	if len(dependency.RequestedBy) == 0 {
+		return 0
+	}
+	return len(slices.MinFunc(dependency.RequestedBy, func(a, b []string) int { return len(a) - len(b) }))
+}
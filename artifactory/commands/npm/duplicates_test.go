@@ -0,0 +1,64 @@
+package npm
+
+import (
+	"testing"
+
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+// ms is installed in three versions: 2.0.0 is requested by debug, 2.1.2 by the project itself, and 10.0.0 by send.
+var multiVersionDependencies = []entities.Dependency{
+	{Id: "debug:2.6.9", RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+	{Id: "ms:10.0.0", RequestedBy: [][]string{{"send:0.16.2", "npm-example:0.0.3"}}},
+	{Id: "ms:2.0.0", RequestedBy: [][]string{{"debug:2.6.9", "npm-example:0.0.3"}}},
+	{Id: "ms:2.1.2", RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+	{Id: "send:0.16.2", RequestedBy: [][]string{{"npm-example:0.0.3"}}},
+}
+
+func TestApplyDuplicateVersionPolicy(t *testing.T) {
+	testCases := []struct {
+		name        string
+		policy      DuplicateVersionPolicy
+		expectedIds []string
+	}{
+		{name: "default", expectedIds: []string{"debug:2.6.9", "ms:10.0.0", "ms:2.0.0", "ms:2.1.2", "send:0.16.2"}},
+		{name: "all", policy: AllVersionsPolicy, expectedIds: []string{"debug:2.6.9", "ms:10.0.0", "ms:2.0.0", "ms:2.1.2", "send:0.16.2"}},
+		{name: "highest", policy: HighestVersionPolicy, expectedIds: []string{"debug:2.6.9", "ms:10.0.0", "send:0.16.2"}},
+		{name: "first", policy: FirstVersionPolicy, expectedIds: []string{"debug:2.6.9", "ms:2.1.2", "send:0.16.2"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var ids []string
+			for _, dependency := range applyDuplicateVersionPolicy(multiVersionDependencies, testCase.policy) {
+				ids = append(ids, dependency.Id)
+			}
+			assert.Equal(t, testCase.expectedIds, ids)
+		})
+	}
+}
+
+func TestApplyFirstVersionPolicyWithEqualDepths(t *testing.T) {
+	dependencies := []entities.Dependency{
+		{Id: "ms:2.1.2", RequestedBy: [][]string{{"send:0.16.2", "npm-example:0.0.3"}}},
+		{Id: "ms:2.0.0", RequestedBy: [][]string{{"debug:2.6.9", "npm-example:0.0.3"}}},
+	}
+	assert.Equal(t, []entities.Dependency{dependencies[1]}, applyDuplicateVersionPolicy(dependencies, FirstVersionPolicy))
+}
+
+func TestSaveDependenciesDataWithDuplicateVersionPolicy(t *testing.T) {
+	npmBuild, cleanUp := createTestBuild(t, "npm-duplicate-version-policy-test")
+	defer cleanUp()
+
+	nc := (&NpmCommand{npmBuild: npmBuild, moduleId: "npm-example:0.0.3"}).SetDuplicateVersionPolicy(HighestVersionPolicy)
+	assert.NoError(t, nc.saveDependenciesData(multiVersionDependencies))
+
+	module := getSavedModule(t, "npm-duplicate-version-policy-test")
+	assert.Equal(t, []entities.Dependency{multiVersionDependencies[0], multiVersionDependencies[1], multiVersionDependencies[4]}, module.Dependencies)
+	assert.Equal(t, 3, nc.Result().DependenciesCount)
+}
+
+func TestRunWithInvalidDuplicateVersionPolicy(t *testing.T) {
+	nc := NewNpmInstallCommand().SetRepo("npm-virtual").SetDuplicateVersionPolicy("latest")
+	assert.ErrorContains(t, nc.Run(), "invalid duplicate version policy 'latest'")
+}
@@ -0,0 +1,59 @@
+package npm
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	gitignoreFileName = ".gitignore"
+	// The comment preceding the entries added to .gitignore by the command.
+	gitignoreEntriesComment = "# Temporary files of the JFrog CLI npm commands"
+)
+
+// Appends the given entries to the .gitignore file in the given directory, unless they're already listed there.
+// The .gitignore file is created if it doesn't exist.
+func addGitignoreEntries(dir string, entries []string) error {
+	gitignorePath := filepath.Join(dir, gitignoreFileName)
+	content, err := os.ReadFile(gitignorePath)
+	if err != nil && !os.IsNotExist(err) {
+		return errorutils.CheckError(err)
+	}
+	var existingEntries []string
+	for _, line := range strings.Split(string(content), "\n") {
+		// An entry may be anchored to the .gitignore directory with a leading slash.
+		existingEntries = append(existingEntries, strings.TrimPrefix(strings.TrimSpace(line), "/"))
+	}
+	var missingEntries []string
+	for _, entry := range entries {
+		if !slices.Contains(existingEntries, entry) {
+			missingEntries = append(missingEntries, entry)
+		}
+	}
+	if len(missingEntries) == 0 {
+		return nil
+	}
+	var addition strings.Builder
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		addition.WriteString("\n")
+	}
+	addition.WriteString(gitignoreEntriesComment + "\n")
+	for _, entry := range missingEntries {
+		addition.WriteString(entry + "\n")
+	}
+	log.Debug(fmt.Sprintf("Adding %s to %s", strings.Join(missingEntries, ", "), gitignorePath))
+	//#nosec G302 -- .gitignore is committed with the project, so it's readable by all.
+	gitignore, err := os.OpenFile(gitignorePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return errorutils.CheckError(err)
+	}
+	_, err = gitignore.WriteString(addition.String())
+	return errorutils.CheckError(errors.Join(err, gitignore.Close()))
+}
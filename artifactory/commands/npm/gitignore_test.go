@@ -0,0 +1,92 @@
+package npm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	clientutils "github.com/jfrog/jfrog-client-go/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddGitignoreEntries(t *testing.T) {
+	testCases := []struct {
+		name              string
+		gitignore         *string
+		expectedGitignore string
+	}{
+		{name: "missing", expectedGitignore: gitignoreEntriesComment + "\njfrog.npmrc.backup\n.npmrc\n"},
+		{name: "without trailing newline", gitignore: clientutils.Pointer("node_modules"), expectedGitignore: "node_modules\n" + gitignoreEntriesComment + "\njfrog.npmrc.backup\n.npmrc\n"},
+		{name: "partially listed", gitignore: clientutils.Pointer("node_modules\n/.npmrc\n"), expectedGitignore: "node_modules\n/.npmrc\n" + gitignoreEntriesComment + "\njfrog.npmrc.backup\n"},
+		{name: "listed", gitignore: clientutils.Pointer(".npmrc\n jfrog.npmrc.backup \n"), expectedGitignore: ".npmrc\n jfrog.npmrc.backup \n"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			gitignorePath := filepath.Join(dir, gitignoreFileName)
+			if testCase.gitignore != nil {
+				assert.NoError(t, os.WriteFile(gitignorePath, []byte(*testCase.gitignore), 0644))
+			}
+			assert.NoError(t, addGitignoreEntries(dir, []string{npmrcBackupFileName, npmrcFileName}))
+			content, err := os.ReadFile(gitignorePath)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedGitignore, string(content))
+
+			// Adding the entries again changes nothing.
+			assert.NoError(t, addGitignoreEntries(dir, []string{npmrcBackupFileName, npmrcFileName}))
+			content, err = os.ReadFile(gitignorePath)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedGitignore, string(content))
+		})
+	}
+}
+
+func TestSetRestoreNpmrcFuncWithLeftovers(t *testing.T) {
+	testCases := []struct {
+		name string
+		// The project's npmrc, and the npmrc backup left behind by an interrupted run.
+		npmrc, leftoverBackup string
+		expectedNpmrc         string
+		expectedEntries       []string
+	}{
+		{name: "clean", expectedEntries: []string{npmrcBackupFileName, npmrcFileName}},
+		{name: "project npmrc", npmrc: "registry=https://registry.npmjs.org/\n", expectedNpmrc: "registry=https://registry.npmjs.org/\n", expectedEntries: []string{npmrcBackupFileName}},
+		{name: "leftover backup", npmrc: "//acme.jfrog.io/:_authToken = leftover-token\n", leftoverBackup: "registry=https://registry.npmjs.org/\n",
+			expectedNpmrc: "registry=https://registry.npmjs.org/\n", expectedEntries: []string{npmrcBackupFileName}},
+		// A temporary npmrc left behind by an interrupted run in a project without an npmrc is removed, rather than backed up as the project's npmrc.
+		{name: "leftover temporary npmrc", npmrc: generatedNpmrcMarker + "\n//acme.jfrog.io/:_authToken = leftover-token\n",
+			expectedEntries: []string{npmrcBackupFileName, npmrcFileName}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			workingDirectory := t.TempDir()
+			npmrcPath := filepath.Join(workingDirectory, npmrcFileName)
+			if testCase.npmrc != "" {
+				assert.NoError(t, os.WriteFile(npmrcPath, []byte(testCase.npmrc), 0644))
+			}
+			if testCase.leftoverBackup != "" {
+				assert.NoError(t, os.WriteFile(filepath.Join(workingDirectory, npmrcBackupFileName), []byte(testCase.leftoverBackup), 0644))
+			}
+			nc := NewNpmInstallCommand().SetManageGitignore(true)
+			nc.workingDirectory = workingDirectory
+			assert.NoError(t, nc.setRestoreNpmrcFunc())
+
+			gitignore, err := os.ReadFile(filepath.Join(workingDirectory, gitignoreFileName))
+			assert.NoError(t, err)
+			assert.Equal(t, gitignoreEntriesComment+"\n"+strings.Join(testCase.expectedEntries, "\n")+"\n", string(gitignore))
+
+			// The temporary npmrc is written, and then the project's npmrc is restored.
+			assert.NoError(t, os.WriteFile(npmrcPath, []byte("registry = https://acme.jfrog.io/artifactory/api/npm/npm/\n"), 0644))
+			assert.NoError(t, nc.restoreNpmrcFunc())
+			assert.NoFileExists(t, filepath.Join(workingDirectory, npmrcBackupFileName))
+			if testCase.expectedNpmrc == "" {
+				assert.NoFileExists(t, npmrcPath)
+				return
+			}
+			npmrc, err := os.ReadFile(npmrcPath)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedNpmrc, string(npmrc))
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package npm
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// The parts of package-lock.json which hold the integrities of the dependencies.
+// Lockfile versions 2 and 3 list the dependencies under 'packages', by their paths in node_modules.
+// Lockfile version 1 lists them under 'dependencies', nested by the dependencies which require them.
+type packageLock struct {
+	Packages     map[string]packageLockEntry `json:"packages,omitempty"`
+	Dependencies map[string]packageLockEntry `json:"dependencies,omitempty"`
+}
+
+type packageLockEntry struct {
+	// The name of the package, if it's installed under an alias.
+	Name         string                      `json:"name,omitempty"`
+	Version      string                      `json:"version,omitempty"`
+	Integrity    string                      `json:"integrity,omitempty"`
+	Resolved     string                      `json:"resolved,omitempty"`
+	Dependencies map[string]packageLockEntry `json:"dependencies,omitempty"`
+}
+
+// Reads package-lock.json in the given directory, and returns the integrities of the dependencies, by their IDs.
+func readLockfileIntegrities(srcPath string) (map[string]string, error) {
+	content, err := os.ReadFile(filepath.Join(srcPath, "package-lock.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errorutils.CheckErrorf("verifying the dependencies' checksums against the lockfile requires a package-lock.json in %s", srcPath)
+		}
+		return nil, errorutils.CheckError(err)
+	}
+	var lockfile packageLock
+	if err = json.Unmarshal(content, &lockfile); err != nil {
+		return nil, errorutils.CheckErrorf("failed to parse package-lock.json: %s", err.Error())
+	}
+	integrities := make(map[string]string)
+	for packagePath, entry := range lockfile.Packages {
+		lastNodeModules := strings.LastIndex(packagePath, "node_modules/")
+		if lastNodeModules == -1 || entry.Integrity == "" {
+			// The project itself, or a linked package.
+			continue
+		}
+		name := entry.Name
+		if name == "" {
+			name = packagePath[lastNodeModules+len("node_modules/"):]
+		}
+		integrities[name+":"+entry.Version] = entry.Integrity
+	}
+	var addLegacyDependencies func(dependencies map[string]packageLockEntry)
+	addLegacyDependencies = func(dependencies map[string]packageLockEntry) {
+		for name, entry := range dependencies {
+			if entry.Integrity != "" {
+				integrities[name+":"+entry.Version] = entry.Integrity
+			}
+			addLegacyDependencies(entry.Dependencies)
+		}
+	}
+	if len(lockfile.Packages) == 0 {
+		addLegacyDependencies(lockfile.Dependencies)
+	}
+	return integrities, nil
+}
+
+// Returns an error describing the mismatch if the checksums of the dependency's tarball don't match the given integrity.
+// The integrity may include several hashes (for example, 'sha512-... sha1-...'), all of which must match.
+// The sha1 and sha256 hashes are compared with the calculated checksums, and the sha512 and sha384 hashes are calculated from the tarball.
+// Hashes of other algorithms are ignored.
+func verifyIntegrity(tarballPath string, checksum entities.Checksum, integrity string) error {
+	for _, integrityHash := range strings.Fields(integrity) {
+		algorithm, encodedDigest, found := strings.Cut(integrityHash, "-")
+		if !found {
+			return errorutils.CheckErrorf("invalid integrity '%s'", integrityHash)
+		}
+		// Options may follow the digest, after a question mark.
+		encodedDigest, _, _ = strings.Cut(encodedDigest, "?")
+		digest, err := base64.StdEncoding.DecodeString(encodedDigest)
+		if err != nil {
+			return errorutils.CheckErrorf("invalid integrity '%s': %s", integrityHash, err.Error())
+		}
+		var actualDigest string
+		switch algorithm {
+		case "sha1":
+			actualDigest = checksum.Sha1
+		case "sha256":
+			actualDigest = checksum.Sha256
+		case "sha512":
+			actualDigest, err = calculateFileDigest(tarballPath, sha512.New())
+		case "sha384":
+			actualDigest, err = calculateFileDigest(tarballPath, sha512.New384())
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if expectedDigest := hex.EncodeToString(digest); !strings.EqualFold(actualDigest, expectedDigest) {
+			return fmt.Errorf("the lockfile's %s is %s, but the tarball's is %s", algorithm, expectedDigest, actualDigest)
+		}
+	}
+	return nil
+}
+
+func calculateFileDigest(path string, hasher hash.Hash) (digest string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	defer func() {
+		err = errors.Join(err, errorutils.CheckError(file.Close()))
+	}()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,121 @@
+package npm
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadLockfileIntegrities(t *testing.T) {
+	testCases := []struct {
+		name                string
+		lockfile            string
+		expectedIntegrities map[string]string
+	}{
+		{
+			name: "packages",
+			lockfile: `{"lockfileVersion":3,"packages":{
+				"":{"name":"my-app","version":"1.0.0"},
+				"node_modules/send":{"version":"0.16.2","integrity":"sha512-send"},
+				"node_modules/send/node_modules/ms":{"version":"2.0.0","integrity":"sha512-ms"},
+				"node_modules/@jfrog/pkg":{"version":"1.0.0","integrity":"sha1-pkg"},
+				"node_modules/aliased":{"name":"debug","version":"4.1.1","integrity":"sha512-debug"},
+				"node_modules/linked":{"resolved":"packages/linked","link":true}}}`,
+			expectedIntegrities: map[string]string{"send:0.16.2": "sha512-send", "ms:2.0.0": "sha512-ms", "@jfrog/pkg:1.0.0": "sha1-pkg", "debug:4.1.1": "sha512-debug"},
+		},
+		{
+			name: "legacy dependencies",
+			lockfile: `{"lockfileVersion":1,"dependencies":{
+				"send":{"version":"0.16.2","integrity":"sha512-send","dependencies":{"ms":{"version":"2.0.0","integrity":"sha512-ms"}}}}}`,
+			expectedIntegrities: map[string]string{"send:0.16.2": "sha512-send", "ms:2.0.0": "sha512-ms"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			projectDir := t.TempDir()
+			assert.NoError(t, os.WriteFile(filepath.Join(projectDir, "package-lock.json"), []byte(testCase.lockfile), 0644))
+			integrities, err := readLockfileIntegrities(projectDir)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedIntegrities, integrities)
+		})
+	}
+
+	_, err := readLockfileIntegrities(t.TempDir())
+	assert.ErrorContains(t, err, "requires a package-lock.json")
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	tarballPath := filepath.Join(t.TempDir(), "send-0.16.2.tgz")
+	content := []byte("send tarball")
+	assert.NoError(t, os.WriteFile(tarballPath, content, 0644))
+	sha1Digest, sha512Digest := sha1.Sum(content), sha512.Sum512(content)
+	sha1Integrity := "sha1-" + base64.StdEncoding.EncodeToString(sha1Digest[:])
+	sha512Integrity := "sha512-" + base64.StdEncoding.EncodeToString(sha512Digest[:])
+	otherSha512Digest := sha512.Sum512([]byte("tampered tarball"))
+	checksum := entities.Checksum{Sha1: hex.EncodeToString(sha1Digest[:])}
+
+	testCases := []struct {
+		name          string
+		integrity     string
+		expectedError string
+	}{
+		{name: "sha512", integrity: sha512Integrity},
+		{name: "sha1", integrity: sha1Integrity},
+		{name: "several hashes", integrity: sha512Integrity + " " + sha1Integrity},
+		{name: "unsupported algorithm", integrity: "md5-AAAA"},
+		{name: "sha512 mismatch", integrity: "sha512-" + base64.StdEncoding.EncodeToString(otherSha512Digest[:]), expectedError: "the lockfile's sha512 is"},
+		{name: "sha1 mismatch", integrity: sha512Integrity + " sha1-AAAAAAAAAAAAAAAAAAAAAAAAAAA=", expectedError: "the lockfile's sha1 is 0000"},
+		{name: "invalid", integrity: "sha512", expectedError: "invalid integrity"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := verifyIntegrity(tarballPath, checksum, testCase.integrity)
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestCollectDependenciesChecksumsVerifiedAgainstLockfile(t *testing.T) {
+	tarballs := map[string]string{
+		"send:0.16.2": createTestPackageTarball(t, `{"name":"send","version":"0.16.2"}`),
+		"debug:4.1.1": createTestPackageTarball(t, `{"name":"debug","version":"4.1.1"}`),
+	}
+	locateTarball := func(name, version, integrity string) (string, error) {
+		return tarballs[name+":"+version], nil
+	}
+	dependencies := []npmDependency{
+		{Dependency: entities.Dependency{Id: "send:0.16.2", Scopes: []string{"prod"}}, name: "send", version: "0.16.2"},
+		{Dependency: entities.Dependency{Id: "debug:4.1.1", Scopes: []string{"prod"}}, name: "debug", version: "4.1.1"},
+	}
+	sendContent, err := os.ReadFile(tarballs["send:0.16.2"])
+	assert.NoError(t, err)
+	sendDigest := sha512.Sum512(sendContent)
+	// The debug tarball doesn't match its integrity in the lockfile.
+	tamperedDigest := sha512.Sum512([]byte("tampered tarball"))
+
+	nc := &NpmCommand{lockfileIntegrities: map[string]string{
+		"send:0.16.2": "sha512-" + base64.StdEncoding.EncodeToString(sendDigest[:]),
+		"debug:4.1.1": "sha512-" + base64.StdEncoding.EncodeToString(tamperedDigest[:]),
+	}}
+	_, err = nc.collectDependenciesChecksums(context.Background(), dependencies, locateTarball)
+	assert.ErrorContains(t, err, "the checksums of the following dependencies don't match their integrity in package-lock.json:\ndebug:4.1.1: the lockfile's sha512 is")
+	assert.NotContains(t, err.Error(), "send:0.16.2")
+
+	// Without the debug integrity, the verification passes.
+	delete(nc.lockfileIntegrities, "debug:4.1.1")
+	collected, err := nc.collectDependenciesChecksums(context.Background(), dependencies, locateTarball)
+	assert.NoError(t, err)
+	assert.Len(t, collected, 2)
+}
@@ -0,0 +1,59 @@
+package npm
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// The prefix of the build-info module properties which hold the licenses of the dependencies, followed by the dependency ID.
+// For example: "license.send:0.16.2" = "MIT".
+const licenseModulePropertyPrefix = "license."
+
+// The license fields of a package.json file.
+// The 'license' field is usually an SPDX expression, but may also be an object in old packages.
+// The deprecated 'licenses' field holds a list of license objects.
+type packageLicense struct {
+	License  json.RawMessage `json:"license,omitempty"`
+	Licenses []struct {
+		Type string `json:"type,omitempty"`
+	} `json:"licenses,omitempty"`
+}
+
+// Returns the license of the npm package in the given tarball, as declared in its package.json.
+// An empty string is returned if the package doesn't declare a license.
+func readLicenseFromTarball(tarballPath string) (string, error) {
+	packageJson, err := readPackageJsonFromTarball(tarballPath)
+	if err != nil {
+		return "", err
+	}
+	return parsePackageLicense(packageJson)
+}
+
+func parsePackageLicense(packageJson []byte) (string, error) {
+	var parsedLicense packageLicense
+	if err := json.Unmarshal(packageJson, &parsedLicense); err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	if len(parsedLicense.License) > 0 {
+		var license string
+		if json.Unmarshal(parsedLicense.License, &license) == nil {
+			return license, nil
+		}
+		var licenseObject struct {
+			Type string `json:"type,omitempty"`
+		}
+		if json.Unmarshal(parsedLicense.License, &licenseObject) == nil {
+			return licenseObject.Type, nil
+		}
+	}
+	var licenses []string
+	for _, license := range parsedLicense.Licenses {
+		if license.Type != "" {
+			licenses = append(licenses, license.Type)
+		}
+	}
+	// A list of licenses means that the package may be used under any of them.
+	return strings.Join(licenses, " OR "), nil
+}
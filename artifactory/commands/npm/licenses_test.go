@@ -0,0 +1,75 @@
+package npm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePackageLicense(t *testing.T) {
+	testCases := []struct {
+		name            string
+		packageJson     string
+		expectedLicense string
+	}{
+		{name: "spdx expression", packageJson: `{"name":"send","license":"MIT"}`, expectedLicense: "MIT"},
+		{name: "object", packageJson: `{"name":"send","license":{"type":"ISC","url":"https://opensource.org/licenses/ISC"}}`, expectedLicense: "ISC"},
+		{name: "deprecated list", packageJson: `{"name":"send","licenses":[{"type":"MIT"},{"type":"Apache-2.0"}]}`, expectedLicense: "MIT OR Apache-2.0"},
+		{name: "missing", packageJson: `{"name":"send"}`},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			license, err := parsePackageLicense([]byte(testCase.packageJson))
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedLicense, license)
+		})
+	}
+}
+
+func TestCollectDependenciesChecksumsWithLicenses(t *testing.T) {
+	tarballs := map[string]string{
+		"send:0.16.2": createTestPackageTarball(t, `{"name":"send","version":"0.16.2","license":"MIT"}`),
+		"debug:4.1.1": createTestPackageTarball(t, `{"name":"debug","version":"4.1.1"}`),
+	}
+	locateTarball := func(name, version, integrity string) (string, error) {
+		return tarballs[name+":"+version], nil
+	}
+	dependencies := []npmDependency{
+		{Dependency: entities.Dependency{Id: "send:0.16.2", Scopes: []string{"prod"}}, name: "send", version: "0.16.2"},
+		{Dependency: entities.Dependency{Id: "debug:4.1.1", Scopes: []string{"prod"}}, name: "debug", version: "4.1.1"},
+	}
+
+	// Licenses aren't collected by default.
+	nc := &NpmCommand{}
+	_, err := nc.collectDependenciesChecksums(context.Background(), dependencies, locateTarball)
+	assert.NoError(t, err)
+	assert.Empty(t, nc.moduleProperties)
+
+	nc.SetCollectLicenses(true)
+	collected, err := nc.collectDependenciesChecksums(context.Background(), dependencies, locateTarball)
+	assert.NoError(t, err)
+	assert.Len(t, collected, 2)
+	assert.Equal(t, map[string]string{licenseModulePropertyPrefix + "send:0.16.2": "MIT"}, nc.moduleProperties)
+}
+
+// Creates an npm package tarball containing the given package.json, and returns its path.
+func createTestPackageTarball(t *testing.T, packageJson string) string {
+	tarballPath := filepath.Join(t.TempDir(), "package.tgz")
+	tarball, err := os.Create(tarballPath)
+	assert.NoError(t, err)
+	gzipWriter := gzip.NewWriter(tarball)
+	tarWriter := tar.NewWriter(gzipWriter)
+	assert.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "package/package.json", Mode: 0644, Size: int64(len(packageJson))}))
+	_, err = tarWriter.Write([]byte(packageJson))
+	assert.NoError(t, err)
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, gzipWriter.Close())
+	assert.NoError(t, tarball.Close())
+	return tarballPath
+}
@@ -0,0 +1,332 @@
+package npm
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	biUtils "github.com/jfrog/build-info-go/build/utils"
+	"github.com/jfrog/build-info-go/entities"
+	commandUtils "github.com/jfrog/jfrog-cli-core/v2/artifactory/commands/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/io/fileutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// The fields of package.json which are read while scanning node_modules.
+type packageManifest struct {
+	Name                 string            `json:"name,omitempty"`
+	Version              string            `json:"version,omitempty"`
+	Dependencies         map[string]string `json:"dependencies,omitempty"`
+	DevDependencies      map[string]string `json:"devDependencies,omitempty"`
+	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
+	PeerDependencies     map[string]string `json:"peerDependencies,omitempty"`
+	// Written to the package.json files in node_modules by npm 6 and below.
+	Integrity string `json:"_integrity,omitempty"`
+	Resolved  string `json:"_resolved,omitempty"`
+}
+
+// A package installed in node_modules.
+type installedPackage struct {
+	manifest *packageManifest
+	// The directory in which the package is installed.
+	dir string
+	// True if the package is a local package linked into node_modules, whose dependencies aren't installed in the project.
+	local bool
+}
+
+func (pkg *installedPackage) id() string {
+	return pkg.manifest.Name + ":" + pkg.manifest.Version
+}
+
+// Reads package.json in the given directory. Returns nil if the directory has no package.json.
+func readPackageManifest(dir string) (*packageManifest, error) {
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errorutils.CheckError(err)
+	}
+	manifest := new(packageManifest)
+	if err = json.Unmarshal(content, manifest); err != nil {
+		return nil, errorutils.CheckErrorf("failed to parse %s: %s", filepath.Join(dir, "package.json"), err.Error())
+	}
+	return manifest, nil
+}
+
+// Reads the packages installed in the node_modules directory of the given directory, and in their nested node_modules directories,
+// and adds them to the given map, by their directories. Hidden entries, such as .bin and .package-lock.json, are skipped.
+func scanNodeModules(dir string, packages map[string]*installedPackage) error {
+	nodeModulesDir := filepath.Join(dir, "node_modules")
+	entries, err := os.ReadDir(nodeModulesDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errorutils.CheckError(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if !strings.HasPrefix(entry.Name(), "@") {
+			if err = readInstalledPackage(filepath.Join(nodeModulesDir, entry.Name()), entry, packages); err != nil {
+				return err
+			}
+			continue
+		}
+		// The packages of a scope are installed in the scope's directory.
+		scopeDir := filepath.Join(nodeModulesDir, entry.Name())
+		scopedEntries, err := os.ReadDir(scopeDir)
+		if err != nil {
+			return errorutils.CheckError(err)
+		}
+		for _, scopedEntry := range scopedEntries {
+			if err = readInstalledPackage(filepath.Join(scopeDir, scopedEntry.Name()), scopedEntry, packages); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readInstalledPackage(packageDir string, entry os.DirEntry, packages map[string]*installedPackage) error {
+	local := entry.Type()&os.ModeSymlink != 0
+	if !local && !entry.IsDir() {
+		return nil
+	}
+	manifest, err := readPackageManifest(packageDir)
+	if err != nil {
+		return err
+	}
+	if manifest == nil || manifest.Name == "" || manifest.Version == "" {
+		log.Debug("Skipping", packageDir, "while scanning node_modules, since its package.json is missing or has no name or version.")
+		return nil
+	}
+	packages[packageDir] = &installedPackage{manifest: manifest, dir: packageDir, local: local}
+	if local {
+		return nil
+	}
+	return scanNodeModules(packageDir, packages)
+}
+
+// npm 7 and above don't write the integrity and tarball URL of the installed packages to their package.json files, but to the hidden lockfile,
+// node_modules/.package-lock.json, which describes the installed node_modules rather than the project. Adds these details to the packages
+// which are missing them, if the hidden lockfile exists and is readable.
+func addHiddenLockfileDetails(projectDir string, packages map[string]*installedPackage) {
+	hiddenLockfilePath := filepath.Join(projectDir, "node_modules", ".package-lock.json")
+	content, err := os.ReadFile(hiddenLockfilePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debug("Couldn't read", hiddenLockfilePath+":", err.Error())
+		}
+		return
+	}
+	var hiddenLockfile packageLock
+	if err = json.Unmarshal(content, &hiddenLockfile); err != nil {
+		log.Debug("Couldn't parse", hiddenLockfilePath+":", err.Error())
+		return
+	}
+	for packagePath, entry := range hiddenLockfile.Packages {
+		pkg, exists := packages[filepath.Join(projectDir, filepath.FromSlash(packagePath))]
+		if !exists || pkg.manifest.Version != entry.Version {
+			continue
+		}
+		if pkg.manifest.Integrity == "" {
+			pkg.manifest.Integrity = entry.Integrity
+		}
+		if pkg.manifest.Resolved == "" {
+			pkg.manifest.Resolved = entry.Resolved
+		}
+	}
+}
+
+// Returns the installed package which is required by the given name from the given directory, according to the Node.js resolution:
+// the package is searched in the node_modules directory of the directory, and then of its ancestors, up to the project's directory.
+func resolveInstalledPackage(fromDir, projectDir, name string, packages map[string]*installedPackage) *installedPackage {
+	for dir := fromDir; ; dir = filepath.Dir(dir) {
+		if pkg, exists := packages[filepath.Join(dir, "node_modules", name)]; exists {
+			return pkg
+		}
+		if dir == projectDir || dir == filepath.Dir(dir) {
+			return nil
+		}
+	}
+}
+
+// An edge of the dependencies tree, from the package which requires the dependency.
+type requiredPackage struct {
+	pkg *installedPackage
+	// The requestedBy path of the requiring package, starting with the requiring package.
+	requestedBy []string
+	optional    bool
+}
+
+// Calculates the project's dependencies by scanning the packages installed in its node_modules, and reading their package.json files,
+// which doesn't require a lockfile or 'npm ls'. The dependencies tree is built by resolving the dependencies in package.json like Node.js does.
+// The dependencies required through the project's dependencies are in the prod scope, and the ones required through its dev dependencies are
+// in the dev scope. Installed packages which aren't required by the project are collected as direct dependencies without a scope.
+// Returns the dependencies, and their tarball URLs, if npm wrote them to the package.json files or to the hidden lockfile, by the dependencies' IDs.
+func scanNodeModulesDependencies(projectDir, moduleId string) ([]npmDependency, map[string]string, error) {
+	packages := make(map[string]*installedPackage)
+	if err := scanNodeModules(projectDir, packages); err != nil {
+		return nil, nil, err
+	}
+	rootManifest, err := readPackageManifest(projectDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	addHiddenLockfileDetails(projectDir, packages)
+	dependencies := make(map[string]*npmDependency)
+	// Whether each collected dependency is required by a non-optional edge.
+	required := make(map[string]bool)
+	addDependency := func(edge requiredPackage, scope string) (added bool) {
+		id := edge.pkg.id()
+		dep, exists := dependencies[id]
+		if !exists {
+			dep = &npmDependency{Dependency: entities.Dependency{Id: id}, name: edge.pkg.manifest.Name, version: edge.pkg.manifest.Version,
+				integrity: edge.pkg.manifest.Integrity, local: edge.pkg.local}
+			if dep.local {
+				dep.Scopes = append(dep.Scopes, localDependencyScope)
+			}
+			dependencies[id] = dep
+		}
+		required[id] = required[id] || !edge.optional
+		if scope != "" && !slices.Contains(dep.Scopes, scope) {
+			dep.Scopes = append(dep.Scopes, scope)
+			added = true
+		}
+		if !slices.ContainsFunc(dep.RequestedBy, func(path []string) bool { return slices.Equal(path, edge.requestedBy) }) {
+			dep.RequestedBy = append(dep.RequestedBy, edge.requestedBy)
+		}
+		return added || !exists
+	}
+	// Walks the tree breadth-first from the given edges, so each package's requestedBy path is the shortest path to it.
+	walk := func(edges []requiredPackage, scope string) {
+		for len(edges) > 0 {
+			edge := edges[0]
+			edges = edges[1:]
+			if !addDependency(edge, scope) || edge.pkg.local {
+				continue
+			}
+			requestedBy := append([]string{edge.pkg.id()}, edge.requestedBy...)
+			edges = append(edges, getRequiredPackages(edge.pkg.manifest, edge.pkg.dir, projectDir, requestedBy, packages, false)...)
+		}
+	}
+	rootRequestedBy := []string{moduleId}
+	if rootManifest == nil {
+		// Without a package.json, such as in the global node_modules, all the top-level packages are required.
+		topLevelPackages := make(map[string]string)
+		for _, packageDir := range sortedKeys(packages) {
+			if isTopLevelPackage(projectDir, packageDir) {
+				topLevelPackages[packages[packageDir].manifest.Name] = ""
+			}
+		}
+		walk(getRequiredPackages(&packageManifest{Dependencies: topLevelPackages}, projectDir, projectDir, rootRequestedBy, packages, false), "prod")
+	} else {
+		walk(getRequiredPackages(rootManifest, projectDir, projectDir, rootRequestedBy, packages, false), "prod")
+		walk(getRequiredPackages(&packageManifest{Dependencies: rootManifest.DevDependencies}, projectDir, projectDir, rootRequestedBy, packages, false), "dev")
+	}
+	resolvedUrls := make(map[string]string)
+	for _, packageDir := range sortedKeys(packages) {
+		pkg := packages[packageDir]
+		id := pkg.id()
+		if _, exists := dependencies[id]; !exists {
+			log.Debug(id, "is installed in", pkg.dir, "but isn't required by the project. It's collected as a direct dependency.")
+			addDependency(requiredPackage{pkg: pkg, requestedBy: rootRequestedBy}, "")
+		}
+		if pkg.manifest.Resolved != "" {
+			resolvedUrls[id] = pkg.manifest.Resolved
+		}
+	}
+	var result []npmDependency
+	for _, id := range sortedKeys(dependencies) {
+		dependencies[id].optional = !required[id]
+		result = append(result, *dependencies[id])
+	}
+	return result, resolvedUrls, nil
+}
+
+// Returns true if the package is installed at the top of the project's node_modules, either directly or in a scope's directory.
+func isTopLevelPackage(projectDir, packageDir string) bool {
+	parentDir := filepath.Dir(packageDir)
+	if strings.HasPrefix(filepath.Base(parentDir), "@") {
+		parentDir = filepath.Dir(parentDir)
+	}
+	return parentDir == filepath.Join(projectDir, "node_modules")
+}
+
+// Returns the installed packages required by the package.json of the package in the given directory: its dependencies, optional dependencies and installed
+// peer dependencies. Dependencies which aren't installed are skipped. If 'optional' is true, all the returned edges are optional.
+func getRequiredPackages(manifest *packageManifest, dir, projectDir string, requestedBy []string, packages map[string]*installedPackage, optional bool) []requiredPackage {
+	var edges []requiredPackage
+	addEdges := func(names map[string]string, optionalNames bool) {
+		for _, name := range sortedKeys(names) {
+			pkg := resolveInstalledPackage(dir, projectDir, name, packages)
+			if pkg == nil {
+				log.Debug(name, "which is required by", requestedBy[0], "isn't installed.")
+				continue
+			}
+			edges = append(edges, requiredPackage{pkg: pkg, requestedBy: requestedBy, optional: optional || optionalNames})
+		}
+	}
+	addEdges(manifest.Dependencies, false)
+	addEdges(manifest.OptionalDependencies, true)
+	addEdges(manifest.PeerDependencies, true)
+	return edges
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := maps.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
+
+// Calculates the project's dependencies by scanning its node_modules rather than by running 'npm ls', and collects their checksums from the local npm cache.
+func (nc *NpmCommand) calculateDependenciesFromNodeModules(ctx context.Context, srcPath string, npmFlags []string) ([]entities.Dependency, error) {
+	nodeModulesExists, err := fileutils.IsDirExists(filepath.Join(srcPath, "node_modules"), false)
+	if err != nil {
+		return nil, err
+	}
+	if !nodeModulesExists {
+		return nil, errorutils.CheckErrorf("the '%s' collection strategy requires the installed node_modules, which is missing in %s", NodeModulesScanCollectionStrategy, srcPath)
+	}
+	scannedDependencies, resolvedUrls, err := scanNodeModulesDependencies(srcPath, nc.moduleId)
+	if err != nil {
+		return nil, err
+	}
+	maxDepth := nc.getMaxDepth()
+	var dependencies []npmDependency
+	for _, dep := range scannedDependencies {
+		if nc.productionOnly && !slices.Contains(dep.Scopes, "prod") {
+			continue
+		}
+		// The depth of the direct dependencies is 0 in 'npm ls', and 1 in getDependencyDepth.
+		if maxDepth != nil && getDependencyDepth(dep.Dependency) > *maxDepth+1 {
+			continue
+		}
+		dependencies = append(dependencies, dep)
+	}
+	if nc.global {
+		dependencies = filterInstalledGlobalDependencies(dependencies, filterFlags(nc.npmArgs))
+	}
+	if dependencies, err = nc.limitDependencies(dependencies); err != nil {
+		return nil, err
+	}
+	cacheLocation, err := biUtils.GetNpmConfigCache(nc.workingDirectory, nc.executablePath, npmFlags, log.Logger)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	resolveMissingIntegrities(dependencies, resolvedUrls, cacheLocation)
+	if nc.verifyAgainstLockfile {
+		if nc.lockfileIntegrities, err = readLockfileIntegrities(srcPath); err != nil {
+			return nil, err
+		}
+	}
+	return nc.collectDependenciesChecksums(ctx, dependencies, commandUtils.NewNpmCacheTarballLocator(cacheLocation))
+}
@@ -0,0 +1,96 @@
+package npm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+const nodeModulesScanModuleId = "npm-test-project:1.0.0"
+
+// Creates a sample project with an installed node_modules tree, and returns the project's directory.
+// a requires c 2.0.0, which is nested in its node_modules, while @scope/b and the dev dependency d require the top-level c 1.0.0.
+// linked is a local package linked into node_modules, and extraneous isn't required by the project.
+func createNodeModulesScanProject(t *testing.T, withRootPackageJson bool) string {
+	tempDir := t.TempDir()
+	projectDir := filepath.Join(tempDir, "project")
+	writePackageJson := func(dir, content string) {
+		assert.NoError(t, os.MkdirAll(dir, 0755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(content), 0644))
+	}
+	if withRootPackageJson {
+		writePackageJson(projectDir, `{"name":"npm-test-project","version":"1.0.0","dependencies":{"a":"^1.0.0","@scope/b":"1.0.0","linked":"file:../linked-pkg"},
+			"devDependencies":{"d":"1.0.0"},"optionalDependencies":{"e":"1.0.0"}}`)
+	}
+	nodeModules := filepath.Join(projectDir, "node_modules")
+	writePackageJson(filepath.Join(nodeModules, "a"), `{"name":"a","version":"1.0.0","dependencies":{"c":"^2.0.0"},
+		"_integrity":"sha512-a","_resolved":"https://registry.example.com/a/-/a-1.0.0.tgz"}`)
+	writePackageJson(filepath.Join(nodeModules, "a", "node_modules", "c"), `{"name":"c","version":"2.0.0"}`)
+	writePackageJson(filepath.Join(nodeModules, "@scope", "b"), `{"name":"@scope/b","version":"1.0.0","dependencies":{"c":"^1.0.0","missing":"1.0.0"}}`)
+	writePackageJson(filepath.Join(nodeModules, "c"), `{"name":"c","version":"1.0.0"}`)
+	writePackageJson(filepath.Join(nodeModules, "d"), `{"name":"d","version":"1.0.0","dependencies":{"c":"^1.0.0"}}`)
+	writePackageJson(filepath.Join(nodeModules, "e"), `{"name":"e","version":"1.0.0"}`)
+	writePackageJson(filepath.Join(nodeModules, "extraneous"), `{"name":"extraneous","version":"1.0.0"}`)
+	// Entries which aren't packages.
+	writePackageJson(filepath.Join(nodeModules, "no-version"), `{"name":"no-version"}`)
+	assert.NoError(t, os.MkdirAll(filepath.Join(nodeModules, ".bin"), 0755))
+	// The hidden lockfile of npm 7 and above. The entry of e doesn't match its installed version, so it's ignored.
+	assert.NoError(t, os.WriteFile(filepath.Join(nodeModules, ".package-lock.json"), []byte(`{"packages":{
+		"node_modules/a/node_modules/c":{"version":"2.0.0","integrity":"sha512-c","resolved":"https://registry.example.com/c/-/c-2.0.0.tgz"},
+		"node_modules/e":{"version":"0.9.0","integrity":"sha512-e"}}}`), 0644))
+	// The dependencies of a linked package are installed in its own directory, so they're not collected.
+	linkedDir := filepath.Join(tempDir, "linked-pkg")
+	writePackageJson(linkedDir, `{"name":"linked","version":"0.1.0","dependencies":{"c":"^1.0.0"}}`)
+	writePackageJson(filepath.Join(linkedDir, "node_modules", "c"), `{"name":"c","version":"1.5.0"}`)
+	assert.NoError(t, os.Symlink(linkedDir, filepath.Join(nodeModules, "linked")))
+	return projectDir
+}
+
+func TestScanNodeModulesDependencies(t *testing.T) {
+	projectDir := createNodeModulesScanProject(t, true)
+	dependencies, resolvedUrls, err := scanNodeModulesDependencies(projectDir, nodeModulesScanModuleId)
+	assert.NoError(t, err)
+	newDependency := func(id string, scopes []string, requestedBy ...[]string) entities.Dependency {
+		return entities.Dependency{Id: id, Scopes: scopes, RequestedBy: requestedBy}
+	}
+	assert.Equal(t, []npmDependency{
+		{Dependency: newDependency("@scope/b:1.0.0", []string{"prod"}, []string{nodeModulesScanModuleId}), name: "@scope/b", version: "1.0.0"},
+		{Dependency: newDependency("a:1.0.0", []string{"prod"}, []string{nodeModulesScanModuleId}), name: "a", version: "1.0.0", integrity: "sha512-a"},
+		{Dependency: newDependency("c:1.0.0", []string{"prod", "dev"}, []string{"@scope/b:1.0.0", nodeModulesScanModuleId}, []string{"d:1.0.0", nodeModulesScanModuleId}),
+			name: "c", version: "1.0.0"},
+		{Dependency: newDependency("c:2.0.0", []string{"prod"}, []string{"a:1.0.0", nodeModulesScanModuleId}), name: "c", version: "2.0.0", integrity: "sha512-c"},
+		{Dependency: newDependency("d:1.0.0", []string{"dev"}, []string{nodeModulesScanModuleId}), name: "d", version: "1.0.0"},
+		{Dependency: newDependency("e:1.0.0", []string{"prod"}, []string{nodeModulesScanModuleId}), name: "e", version: "1.0.0", optional: true},
+		{Dependency: newDependency("extraneous:1.0.0", nil, []string{nodeModulesScanModuleId}), name: "extraneous", version: "1.0.0"},
+		{Dependency: newDependency("linked:0.1.0", []string{localDependencyScope, "prod"}, []string{nodeModulesScanModuleId}), name: "linked", version: "0.1.0", local: true},
+	}, dependencies)
+	assert.Equal(t, map[string]string{
+		"a:1.0.0": "https://registry.example.com/a/-/a-1.0.0.tgz",
+		"c:2.0.0": "https://registry.example.com/c/-/c-2.0.0.tgz",
+	}, resolvedUrls)
+}
+
+func TestScanNodeModulesDependenciesWithoutPackageJson(t *testing.T) {
+	// Without a package.json, such as in the global node_modules, the top-level packages are the direct dependencies.
+	projectDir := createNodeModulesScanProject(t, false)
+	dependencies, _, err := scanNodeModulesDependencies(projectDir, nodeModulesScanModuleId)
+	assert.NoError(t, err)
+	requestedBy := make(map[string][][]string)
+	for _, dependency := range dependencies {
+		requestedBy[dependency.Id] = dependency.RequestedBy
+		assert.Equal(t, []string{"prod"}, dependency.Scopes[len(dependency.Scopes)-1:], dependency.Id)
+	}
+	assert.Equal(t, map[string][][]string{
+		"@scope/b:1.0.0":   {{nodeModulesScanModuleId}},
+		"a:1.0.0":          {{nodeModulesScanModuleId}},
+		"c:1.0.0":          {{nodeModulesScanModuleId}, {"@scope/b:1.0.0", nodeModulesScanModuleId}, {"d:1.0.0", nodeModulesScanModuleId}},
+		"c:2.0.0":          {{"a:1.0.0", nodeModulesScanModuleId}},
+		"d:1.0.0":          {{nodeModulesScanModuleId}},
+		"e:1.0.0":          {{nodeModulesScanModuleId}},
+		"extraneous:1.0.0": {{nodeModulesScanModuleId}},
+		"linked:0.1.0":     {{nodeModulesScanModuleId}},
+	}, requestedBy)
+}
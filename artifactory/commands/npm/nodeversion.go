@@ -0,0 +1,110 @@
+package npm
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jfrog/gofrog/version"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+// A node version manager, which installs each node version, along with its npm, in a directory of its own.
+type nodeVersionManager struct {
+	name string
+	// Returns the directory in which the node versions are installed, or an empty string if it can't be determined.
+	versionsDir func() string
+	// The path of the directory of the node and npm executables, relative to the directory of the node version.
+	binDir string
+}
+
+// The supported node version managers, in the order in which they are searched for the requested node version:
+// nvm, whose versions are installed under $NVM_DIR (~/.nvm by default), and fnm, whose versions are installed under $FNM_DIR (~/.local/share/fnm by default).
+var nodeVersionManagers = []nodeVersionManager{
+	{
+		name:        "nvm",
+		versionsDir: func() string { return getVersionManagerDir("NVM_DIR", ".nvm", "versions", "node") },
+		binDir:      "bin",
+	},
+	{
+		name: "fnm",
+		versionsDir: func() string {
+			return getVersionManagerDir("FNM_DIR", filepath.Join(".local", "share", "fnm"), "node-versions")
+		},
+		binDir: filepath.Join("installation", "bin"),
+	},
+}
+
+// Returns the versions directory under the version manager's directory, which is set by the given environment variable,
+// or is the given directory under the user's home directory by default.
+func getVersionManagerDir(dirEnv, defaultHomeSubDir string, versionsSubDir ...string) string {
+	managerDir := os.Getenv(dirEnv)
+	if managerDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		managerDir = filepath.Join(homeDir, defaultHomeSubDir)
+	}
+	return filepath.Join(append([]string{managerDir}, versionsSubDir...)...)
+}
+
+// Returns the directory of the node and npm executables of the requested node version, as installed by one of the supported version managers.
+// The requested version may be partial (for example, '18' or 'v18.20'), in which case the latest installed matching version is used.
+func findNodeBinDir(requestedVersion string) (string, error) {
+	requestedVersion = strings.TrimPrefix(strings.TrimSpace(requestedVersion), "v")
+	for _, manager := range nodeVersionManagers {
+		versionsDir := manager.versionsDir()
+		if versionsDir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(versionsDir)
+		if err != nil {
+			continue
+		}
+		var matchingVersion, matchingDir string
+		for _, entry := range entries {
+			installedVersion := strings.TrimPrefix(entry.Name(), "v")
+			if !entry.IsDir() || (installedVersion != requestedVersion && !strings.HasPrefix(installedVersion, requestedVersion+".")) {
+				continue
+			}
+			if matchingVersion == "" || version.NewVersion(installedVersion).AtLeast(matchingVersion) {
+				matchingVersion, matchingDir = installedVersion, entry.Name()
+			}
+		}
+		if matchingVersion != "" {
+			binDir := filepath.Join(versionsDir, matchingDir, manager.binDir)
+			log.Debug(fmt.Sprintf("Using node %s installed by %s in %s", matchingVersion, manager.name, binDir))
+			return binDir, nil
+		}
+	}
+	return "", errorutils.CheckErrorf("node version '%s' isn't installed by any of the supported node version managers (nvm, fnm)", requestedVersion)
+}
+
+// Allows mocking the resolution of the node version in tests.
+var resolveNodeBinDir = findNodeBinDir
+
+// Puts the node and npm executables of the requested node version first in the PATH, so they are used by all the npm commands of the run,
+// including the npm scripts of the installed packages. Returns a function which restores the PATH.
+func useNodeVersion(nodeVersion string) (restorePathFunc func() error, err error) {
+	binDir, err := resolveNodeBinDir(nodeVersion)
+	if err != nil {
+		return nil, err
+	}
+	originalPath, pathExists := os.LookupEnv("PATH")
+	newPath := binDir
+	if originalPath != "" {
+		newPath += string(os.PathListSeparator) + originalPath
+	}
+	if err = os.Setenv("PATH", newPath); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	return func() error {
+		if !pathExists {
+			return errorutils.CheckError(os.Unsetenv("PATH"))
+		}
+		return errorutils.CheckError(os.Setenv("PATH", originalPath))
+	}, nil
+}
@@ -0,0 +1,91 @@
+package npm
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	biUtils "github.com/jfrog/build-info-go/build/utils"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindNodeBinDir(t *testing.T) {
+	nvmDir, fnmDir := t.TempDir(), t.TempDir()
+	t.Setenv("NVM_DIR", nvmDir)
+	t.Setenv("FNM_DIR", fnmDir)
+	for _, versionDir := range []string{
+		filepath.Join(nvmDir, "versions", "node", "v18.9.0", "bin"),
+		filepath.Join(nvmDir, "versions", "node", "v18.20.4", "bin"),
+		filepath.Join(nvmDir, "versions", "node", "v180.0.0", "bin"),
+		filepath.Join(fnmDir, "node-versions", "v20.11.1", "installation", "bin"),
+		filepath.Join(fnmDir, "node-versions", "v18.20.4", "installation", "bin"),
+	} {
+		assert.NoError(t, os.MkdirAll(versionDir, 0755))
+	}
+
+	testCases := []struct {
+		requestedVersion string
+		expectedBinDir   string
+		expectedError    bool
+	}{
+		{requestedVersion: "18.20.4", expectedBinDir: filepath.Join(nvmDir, "versions", "node", "v18.20.4", "bin")},
+		// The latest matching version is used, and nvm is searched before fnm.
+		{requestedVersion: "v18", expectedBinDir: filepath.Join(nvmDir, "versions", "node", "v18.20.4", "bin")},
+		{requestedVersion: "18.9", expectedBinDir: filepath.Join(nvmDir, "versions", "node", "v18.9.0", "bin")},
+		{requestedVersion: "20", expectedBinDir: filepath.Join(fnmDir, "node-versions", "v20.11.1", "installation", "bin")},
+		{requestedVersion: "22", expectedError: true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.requestedVersion, func(t *testing.T) {
+			binDir, err := findNodeBinDir(testCase.requestedVersion)
+			if testCase.expectedError {
+				assert.ErrorContains(t, err, "node version '22' isn't installed")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedBinDir, binDir)
+		})
+	}
+}
+
+func TestUseNodeVersion(t *testing.T) {
+	if coreutils.IsWindows() {
+		t.Skip("The fake npm executable is a shell script.")
+	}
+	previousResolveNodeBinDir := resolveNodeBinDir
+	defer func() {
+		resolveNodeBinDir = previousResolveNodeBinDir
+	}()
+	// A fake npm of the pinned node version.
+	binDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(binDir, "npm"), []byte("#!/bin/sh\necho 9.9.9\n"), 0755))
+	resolveNodeBinDir = func(nodeVersion string) (string, error) {
+		assert.Equal(t, "18", nodeVersion)
+		return binDir, nil
+	}
+	originalPath := os.Getenv("PATH")
+
+	restorePathFunc, err := useNodeVersion("18")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(os.Getenv("PATH"), binDir+string(os.PathListSeparator)))
+	npmVersion, executablePath, err := biUtils.GetNpmVersionAndExecPath(log.Logger)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(binDir, "npm"), executablePath)
+	assert.Equal(t, "9.9.9", npmVersion.GetVersion())
+
+	assert.NoError(t, restorePathFunc())
+	assert.Equal(t, originalPath, os.Getenv("PATH"))
+}
+
+func TestRunWithMissingNodeVersion(t *testing.T) {
+	t.Setenv("NVM_DIR", t.TempDir())
+	t.Setenv("FNM_DIR", t.TempDir())
+	originalPath := os.Getenv("PATH")
+	nc := NewNpmInstallCommand().SetNodeVersion("18")
+	nc.repo = "my-npm-repo"
+	assert.ErrorContains(t, nc.Run(), "node version '18' isn't installed")
+	assert.Equal(t, originalPath, os.Getenv("PATH"))
+}
@@ -1,8 +1,6 @@
 package npm
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"errors"
 	"fmt"
 	"github.com/jfrog/build-info-go/build"
@@ -25,7 +23,6 @@ import (
 	"github.com/jfrog/jfrog-client-go/utils/errorutils"
 	"github.com/jfrog/jfrog-client-go/utils/io/content"
 	"github.com/jfrog/jfrog-client-go/utils/log"
-	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -454,36 +451,12 @@ func (npc *NpmPublishCommand) setPackageInfo() error {
 
 func (npc *NpmPublishCommand) readPackageInfoFromTarball(packedFilePath string) (err error) {
 	log.Debug("Extracting info from npm package:", packedFilePath)
-	tarball, err := os.Open(packedFilePath)
+	packageJson, err := readPackageJsonFromTarball(packedFilePath)
 	if err != nil {
-		return errorutils.CheckError(err)
-	}
-	defer func() {
-		err = errors.Join(err, errorutils.CheckError(tarball.Close()))
-	}()
-	gZipReader, err := gzip.NewReader(tarball)
-	if err != nil {
-		return errorutils.CheckError(err)
-	}
-
-	tarReader := tar.NewReader(gZipReader)
-	for {
-		hdr, err := tarReader.Next()
-		if err != nil {
-			if err == io.EOF {
-				return errorutils.CheckErrorf("Could not find 'package.json' in the compressed npm package: " + packedFilePath)
-			}
-			return errorutils.CheckError(err)
-		}
-		if hdr.Name == "package/package.json" {
-			packageJson, err := io.ReadAll(tarReader)
-			if err != nil {
-				return errorutils.CheckError(err)
-			}
-			npc.packageInfo, err = biutils.ReadPackageInfo(packageJson, npc.npmVersion)
-			return err
-		}
+		return err
 	}
+	npc.packageInfo, err = biutils.ReadPackageInfo(packageJson, npc.npmVersion)
+	return err
 }
 
 func deleteCreatedTarball(packedFilesPath []string) error {
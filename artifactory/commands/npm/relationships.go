@@ -0,0 +1,90 @@
+package npm
+
+import (
+	"encoding/json"
+
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// The prefix of the build-info module properties which hold the relationship types of the edges of the dependencies tree,
+// followed by the ID of the requesting package and the ID of the dependency, separated by '->'.
+// For example: "relationship.my-app:1.0.0->react:18.2.0" = "peer".
+// Only the edges of peer, optional and dev dependencies are saved. Edges without a property are of regular dependencies.
+const (
+	relationshipModulePropertyPrefix = "relationship."
+	relationshipEdgeSeparator        = "->"
+)
+
+// The relationship types of the edges of the dependencies tree, as declared in the package.json of the requesting package.
+const (
+	peerRelationship         = "peer"
+	peerOptionalRelationship = "peerOptional"
+	optionalRelationship     = "optional"
+	devRelationship          = "dev"
+)
+
+// An edge of the dependencies tree: a dependency, requested by a package (or by the project itself).
+type dependencyEdge struct {
+	requesterId  string
+	dependencyId string
+}
+
+func (edge dependencyEdge) modulePropertyKey() string {
+	return relationshipModulePropertyPrefix + edge.requesterId + relationshipEdgeSeparator + edge.dependencyId
+}
+
+// An entry of the 'npm ls --json --long' output, which includes the dependencies declared in the package.json of the package.
+type npmLsLongEntry struct {
+	Version              string                        `json:"version,omitempty"`
+	PeerDependencies     map[string]string             `json:"peerDependencies,omitempty"`
+	PeerDependenciesMeta map[string]peerDependencyMeta `json:"peerDependenciesMeta,omitempty"`
+	OptionalDependencies map[string]string             `json:"optionalDependencies,omitempty"`
+	DevDependencies      map[string]string             `json:"devDependencies,omitempty"`
+	Dependencies         map[string]*npmLsLongEntry    `json:"dependencies,omitempty"`
+}
+
+type peerDependencyMeta struct {
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Returns the relationship type of the given dependency of the package, or an empty string if it's a regular dependency.
+func (entry *npmLsLongEntry) getRelationship(name string) string {
+	if _, isPeer := entry.PeerDependencies[name]; isPeer {
+		if entry.PeerDependenciesMeta[name].Optional {
+			return peerOptionalRelationship
+		}
+		return peerRelationship
+	}
+	if _, isOptional := entry.OptionalDependencies[name]; isOptional {
+		return optionalRelationship
+	}
+	if _, isDev := entry.DevDependencies[name]; isDev {
+		return devRelationship
+	}
+	return ""
+}
+
+// Parses the output of 'npm ls --json --long' and returns the relationship types of the edges of the dependencies tree.
+// The edges of regular dependencies, and of dependencies listed without a version, aren't returned.
+func parseDependencyRelationships(npmLsOutput []byte, moduleId string) (map[dependencyEdge]string, error) {
+	root := new(npmLsLongEntry)
+	if err := json.Unmarshal(npmLsOutput, root); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	relationships := make(map[dependencyEdge]string)
+	var walk func(entry *npmLsLongEntry, entryId string)
+	walk = func(entry *npmLsLongEntry, entryId string) {
+		for name, child := range entry.Dependencies {
+			if child == nil || child.Version == "" {
+				continue
+			}
+			childId := name + ":" + child.Version
+			if relationship := entry.getRelationship(name); relationship != "" {
+				relationships[dependencyEdge{requesterId: entryId, dependencyId: childId}] = relationship
+			}
+			walk(child, childId)
+		}
+	}
+	walk(root, moduleId)
+	return relationships, nil
+}
@@ -0,0 +1,74 @@
+package npm
+
+import (
+	"testing"
+
+	"github.com/jfrog/build-info-go/entities"
+	buildInfoUtils "github.com/jfrog/build-info-go/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// A shortened 'npm ls --json --all --long' output, in which the project requests 'a' as a regular dependency, 'b' as optional and 'c' as dev,
+// and 'a' requests 'b' as optional, 'c' as a peer and 'd' as an optional peer.
+const npmLsLongOutput = `{
+  "name": "my-app",
+  "version": "1.0.0",
+  "devDependencies": {"c": "1.0.0"},
+  "optionalDependencies": {"b": "1.0.0"},
+  "peerDependencies": {},
+  "dependencies": {
+    "a": {
+      "version": "1.0.0",
+      "name": "a",
+      "peerDependencies": {"c": "1.0.0", "d": "^2.0.0"},
+      "peerDependenciesMeta": {"d": {"optional": true}},
+      "optionalDependencies": {"b": "1.0.0"},
+      "devDependencies": {},
+      "dependencies": {
+        "b": {"version": "1.0.0", "name": "b"},
+        "c": {"version": "1.0.0", "name": "c"},
+        "d": {"version": "2.1.0", "name": "d"},
+        "e": {}
+      }
+    },
+    "b": {"version": "1.0.0", "name": "b"},
+    "c": {"version": "1.0.0", "name": "c"},
+    "d": {"version": "2.1.0", "name": "d"}
+  }
+}`
+
+func TestParseDependencyRelationships(t *testing.T) {
+	relationships, err := parseDependencyRelationships([]byte(npmLsLongOutput), "my-app:1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, map[dependencyEdge]string{
+		{requesterId: "my-app:1.0.0", dependencyId: "b:1.0.0"}: optionalRelationship,
+		{requesterId: "my-app:1.0.0", dependencyId: "c:1.0.0"}: devRelationship,
+		{requesterId: "a:1.0.0", dependencyId: "b:1.0.0"}:      optionalRelationship,
+		{requesterId: "a:1.0.0", dependencyId: "c:1.0.0"}:      peerRelationship,
+		{requesterId: "a:1.0.0", dependencyId: "d:2.1.0"}:      peerOptionalRelationship,
+	}, relationships)
+
+	_, err = parseDependencyRelationships([]byte("not json"), "my-app:1.0.0")
+	assert.Error(t, err)
+}
+
+func TestCollectDependencyRelationships(t *testing.T) {
+	previousRunNpmCmd := runNpmCmd
+	defer func() {
+		runNpmCmd = previousRunNpmCmd
+	}()
+	runNpmCmd = func(_, _ string, npmArgs []string, _ buildInfoUtils.Log) ([]byte, []byte, error) {
+		assert.Equal(t, []string{"ls", "--json", "--all", "--omit=dev", "--long"}, npmArgs)
+		return []byte(npmLsLongOutput), nil, nil
+	}
+
+	nc := &NpmCommand{moduleId: "my-app:1.0.0"}
+	// 'd' isn't collected, so its edge isn't saved.
+	nc.collectDependencyRelationships([]string{"--omit=dev"}, []entities.Dependency{{Id: "a:1.0.0"}, {Id: "b:1.0.0"}, {Id: "c:1.0.0"}})
+	assert.Equal(t, map[string]string{
+		"relationship.my-app:1.0.0->b:1.0.0": "optional",
+		"relationship.my-app:1.0.0->c:1.0.0": "dev",
+		"relationship.a:1.0.0->b:1.0.0":      "optional",
+		"relationship.a:1.0.0->c:1.0.0":      "peer",
+	}, nc.moduleProperties)
+}
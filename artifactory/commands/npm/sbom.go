@@ -0,0 +1,77 @@
+package npm
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"golang.org/x/exp/slices"
+)
+
+type SbomFormat string
+
+const (
+	CycloneDxJson SbomFormat = "cyclonedx-json"
+)
+
+// ExportSBOM writes the dependencies collected by the command, as saved in the build-info, to the given writer as an SBOM document in the given format.
+// Only the CycloneDX JSON format is currently supported. This is separate from saving the build-info, and should be called after Run().
+func (nc *NpmCommand) ExportSBOM(writer io.Writer, format SbomFormat) error {
+	if format != CycloneDxJson {
+		return errorutils.CheckErrorf("unsupported SBOM format '%s'. Supported formats: %s", format, CycloneDxJson)
+	}
+	return errorutils.CheckError(cdx.NewBOMEncoder(writer, cdx.BOMFileFormatJSON).SetPretty(true).Encode(nc.createCycloneDxBom()))
+}
+
+func (nc *NpmCommand) createCycloneDxBom() *cdx.BOM {
+	bom := cdx.NewBOM()
+	if nc.moduleId != "" {
+		name, version := splitNpmDependencyId(nc.moduleId)
+		bom.Metadata = &cdx.Metadata{Component: &cdx.Component{Type: cdx.ComponentTypeApplication, Name: name, Version: version}}
+	}
+	components := make([]cdx.Component, 0, len(nc.dependencies))
+	for _, dependency := range nc.dependencies {
+		components = append(components, createCycloneDxComponent(dependency))
+	}
+	bom.Components = &components
+	return bom
+}
+
+func createCycloneDxComponent(dependency entities.Dependency) cdx.Component {
+	name, version := splitNpmDependencyId(dependency.Id)
+	purl := "pkg:npm/" + escapePurlSegment(name) + "@" + escapePurlSegment(version)
+	if scope, packageName, isScoped := strings.Cut(name, "/"); isScoped {
+		purl = "pkg:npm/" + escapePurlSegment(scope) + "/" + escapePurlSegment(packageName) + "@" + escapePurlSegment(version)
+	}
+	component := cdx.Component{BOMRef: purl, Type: cdx.ComponentTypeLibrary, Name: name, Version: version, PackageURL: purl, Scope: cdx.ScopeRequired}
+	// Dependencies which are only used for development are not required at runtime.
+	if slices.Contains(dependency.Scopes, "dev") && !slices.Contains(dependency.Scopes, "prod") {
+		component.Scope = cdx.ScopeOptional
+	}
+	var hashes []cdx.Hash
+	for _, hash := range []cdx.Hash{{Algorithm: cdx.HashAlgoSHA1, Value: dependency.Sha1}, {Algorithm: cdx.HashAlgoSHA256, Value: dependency.Sha256}, {Algorithm: cdx.HashAlgoMD5, Value: dependency.Md5}} {
+		if hash.Value != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	if len(hashes) > 0 {
+		component.Hashes = &hashes
+	}
+	return component
+}
+
+// Percent-encodes a package URL segment. The '@' character of npm scopes must be encoded as well.
+func escapePurlSegment(segment string) string {
+	return strings.ReplaceAll(url.PathEscape(segment), "@", "%40")
+}
+
+// Splits an npm dependency ID (name:version) to the package name and version. The name may contain a scope, such as @jfrog/package.
+func splitNpmDependencyId(id string) (name, version string) {
+	if i := strings.LastIndex(id, ":"); i != -1 {
+		return id[:i], id[i+1:]
+	}
+	return id, ""
+}
@@ -0,0 +1,48 @@
+package npm
+
+import (
+	"bytes"
+	"testing"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/jfrog/build-info-go/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportSBOM(t *testing.T) {
+	nc := &NpmCommand{moduleId: "npm-example:0.0.3", dependencies: []entities.Dependency{
+		{Id: "@jfrog/package:1.0.0", Scopes: []string{"@jfrog", "prod"}, Checksum: entities.Checksum{Sha1: "sha1-value", Md5: "md5-value", Sha256: "sha256-value"}},
+		{Id: "debug:4.1.1", Scopes: []string{"dev"}},
+	}}
+	var buffer bytes.Buffer
+	assert.NoError(t, nc.ExportSBOM(&buffer, CycloneDxJson))
+
+	bom := new(cdx.BOM)
+	assert.NoError(t, cdx.NewBOMDecoder(&buffer, cdx.BOMFileFormatJSON).Decode(bom))
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	if assert.NotNil(t, bom.Metadata) && assert.NotNil(t, bom.Metadata.Component) {
+		assert.Equal(t, "npm-example", bom.Metadata.Component.Name)
+		assert.Equal(t, "0.0.3", bom.Metadata.Component.Version)
+	}
+	if !assert.NotNil(t, bom.Components) || !assert.Len(t, *bom.Components, 2) {
+		return
+	}
+	scopedComponent := (*bom.Components)[0]
+	assert.Equal(t, "@jfrog/package", scopedComponent.Name)
+	assert.Equal(t, "1.0.0", scopedComponent.Version)
+	assert.Equal(t, "pkg:npm/%40jfrog/package@1.0.0", scopedComponent.PackageURL)
+	assert.Equal(t, cdx.ScopeRequired, scopedComponent.Scope)
+	if assert.NotNil(t, scopedComponent.Hashes) {
+		assert.Equal(t, []cdx.Hash{{Algorithm: cdx.HashAlgoSHA1, Value: "sha1-value"}, {Algorithm: cdx.HashAlgoSHA256, Value: "sha256-value"}, {Algorithm: cdx.HashAlgoMD5, Value: "md5-value"}}, *scopedComponent.Hashes)
+	}
+	devComponent := (*bom.Components)[1]
+	assert.Equal(t, "debug", devComponent.Name)
+	assert.Equal(t, "pkg:npm/debug@4.1.1", devComponent.PackageURL)
+	assert.Equal(t, cdx.ScopeOptional, devComponent.Scope)
+	assert.Nil(t, devComponent.Hashes)
+}
+
+func TestExportSBOMUnsupportedFormat(t *testing.T) {
+	var buffer bytes.Buffer
+	assert.ErrorContains(t, (&NpmCommand{}).ExportSBOM(&buffer, "spdx-json"), "unsupported SBOM format")
+}
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	biUtils "github.com/jfrog/build-info-go/build/utils"
+	buildinfo "github.com/jfrog/build-info-go/entities"
+	"github.com/jfrog/gofrog/crypto"
+	gofrogio "github.com/jfrog/gofrog/io"
+	"github.com/jfrog/jfrog-client-go/artifactory"
+	"github.com/jfrog/jfrog-client-go/artifactory/services"
+	servicesUtils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+	"golang.org/x/exp/slices"
+)
+
+type aqlResult struct {
+	Results []*servicesUtils.ResultItem `json:"results,omitempty"`
+}
+
+// ResolveArtifactChecksum searches Artifactory for the artifact of the package with the given name and version,
+// and returns its checksums and its file type (the extension of the artifact's file name).
+// If the artifact could not be found in Artifactory, a nil checksum is returned with no error.
+// This function can be used by any package manager command that collects its dependencies' checksums from Artifactory.
+func ResolveArtifactChecksum(servicesManager artifactory.ArtifactoryServicesManager, name, version string) (checksum *buildinfo.Checksum, fileType string, err error) {
+	checksum, fileType, _, err = ResolveArtifact(servicesManager, name, version, ArtifactSearchCriteria{})
+	return
+}
+
+// ReleaseBundle identifies a version of a release bundle.
+type ReleaseBundle struct {
+	Name    string
+	Version string
+}
+
+// ArtifactSearchCriteria narrows down the search of a package's artifact in Artifactory. The zero value searches all the repositories.
+type ArtifactSearchCriteria struct {
+	// If not empty, only these repositories are searched. See GetProjectRepositories.
+	Repositories []string
+	// If set, only the artifacts of this release bundle version are searched,
+	// which allows resolving the checksums as they were released rather than as they are in Artifactory now.
+	ReleaseBundle *ReleaseBundle
+	// The keys of the artifact's properties to return, such as the name of the build which produced the artifact.
+	// If empty, the properties aren't fetched.
+	PropertyKeys []string
+}
+
+// ResolveArtifact is like ResolveArtifactChecksum, but searches only the artifacts which match the given criteria,
+// and also returns the artifact's properties with the criteria's property keys.
+// Properties with several values are returned with their values joined by commas, and properties which the artifact doesn't have are omitted.
+func ResolveArtifact(servicesManager artifactory.ArtifactoryServicesManager, name, version string,
+	criteria ArtifactSearchCriteria) (checksum *buildinfo.Checksum, fileType string, properties map[string]string, err error) {
+	id := name + ":" + version
+	log.Debug("Fetching checksums for", id)
+	query, err := createArtifactAqlQuery(name, version, criteria)
+	if err != nil {
+		return
+	}
+	stream, err := servicesManager.Aql(query)
+	if err != nil {
+		return
+	}
+	defer gofrogio.Close(stream, &err)
+	result, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, "", nil, errorutils.CheckError(err)
+	}
+	parsedResult := new(aqlResult)
+	if err = json.Unmarshal(result, parsedResult); err != nil {
+		return nil, "", nil, errorutils.CheckError(err)
+	}
+	if len(parsedResult.Results) == 0 {
+		log.Debug(id, "could not be found in Artifactory.")
+		return
+	}
+	artifact := parsedResult.Results[0]
+	if i := strings.LastIndex(artifact.Name, "."); i != -1 {
+		fileType = artifact.Name[i+1:]
+	}
+	log.Debug(id, "was found in Artifactory. Name:", artifact.Name,
+		"SHA-1:", artifact.Actual_Sha1,
+		"MD5:", artifact.Actual_Md5)
+
+	checksum = &buildinfo.Checksum{Sha1: artifact.Actual_Sha1, Md5: artifact.Actual_Md5, Sha256: artifact.Sha256}
+	properties = filterArtifactProperties(artifact.Properties, criteria.PropertyKeys)
+	return
+}
+
+// Returns the values of the properties with the given keys, by the keys. Returns nil if the artifact has none of these properties.
+func filterArtifactProperties(artifactProperties []servicesUtils.Property, propertyKeys []string) map[string]string {
+	var properties map[string]string
+	for _, property := range artifactProperties {
+		if !slices.Contains(propertyKeys, property.Key) {
+			continue
+		}
+		if properties == nil {
+			properties = make(map[string]string)
+		}
+		if value, exists := properties[property.Key]; exists {
+			properties[property.Key] = value + "," + property.Value
+		} else {
+			properties[property.Key] = property.Value
+		}
+	}
+	return properties
+}
+
+// The criteria of the AQL query of a package's artifact. It's marshalled to JSON, so the criteria's values are escaped.
+type artifactAqlCriteria struct {
+	NpmName              string `json:"@npm.name"`
+	ReleaseBundleName    string `json:"release_artifact.release.name,omitempty"`
+	ReleaseBundleVersion string `json:"release_artifact.release.version,omitempty"`
+	// Each of the criteria must match one of its alternatives.
+	And []aqlAlternatives `json:"$and"`
+}
+
+type aqlAlternatives struct {
+	Or []map[string]string `json:"$or"`
+}
+
+// The fields of the searched artifacts, which are included in the AQL results.
+var artifactAqlFields = []string{"name", "repo", "path", "actual_sha1", "actual_md5", "sha256"}
+
+// Returns the AQL query of the npm package's artifact, which matches the given search criteria.
+// The artifact's properties are included only if property keys are given.
+func createArtifactAqlQuery(name, version string, searchCriteria ArtifactSearchCriteria) (string, error) {
+	criteria := artifactAqlCriteria{
+		NpmName: name,
+		// The version of the package may be prefixed with 'v'.
+		And: []aqlAlternatives{{Or: []map[string]string{{"@npm.version": version}, {"@npm.version": "v" + version}}}},
+	}
+	if len(searchCriteria.Repositories) > 0 {
+		repositories := aqlAlternatives{}
+		for _, repository := range searchCriteria.Repositories {
+			repositories.Or = append(repositories.Or, map[string]string{"repo": repository})
+		}
+		criteria.And = append(criteria.And, repositories)
+	}
+	if searchCriteria.ReleaseBundle != nil {
+		criteria.ReleaseBundleName = searchCriteria.ReleaseBundle.Name
+		criteria.ReleaseBundleVersion = searchCriteria.ReleaseBundle.Version
+	}
+	criteriaJson, err := json.Marshal(criteria)
+	if err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	fields := artifactAqlFields
+	if len(searchCriteria.PropertyKeys) > 0 {
+		fields = append([]string{"property.*"}, fields...)
+	}
+	fieldsJson, err := json.Marshal(fields)
+	if err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	// The fields are a JSON array without its brackets.
+	return fmt.Sprintf("items.find(%s).include(%s)", criteriaJson, fieldsJson[1:len(fieldsJson)-1]), nil
+}
+
+// GetProjectRepositories returns the keys of the npm repositories of the JFrog Project with the given key, as they appear in AQL results,
+// so the packages' artifacts can be searched only in the project's repositories.
+// The artifacts cached by remote repositories are in their cache repositories, and virtual repositories have no artifacts of their own.
+func GetProjectRepositories(servicesManager artifactory.ArtifactoryServicesManager, projectKey string) ([]string, error) {
+	repositoriesDetails, err := servicesManager.GetAllRepositoriesFiltered(services.RepositoriesFilterParams{ProjectKey: projectKey, PackageType: "npm"})
+	if err != nil {
+		return nil, err
+	}
+	var repositories []string
+	for _, repositoryDetails := range *repositoriesDetails {
+		switch strings.ToLower(repositoryDetails.GetRepoType()) {
+		case "virtual":
+			continue
+		case "remote":
+			repositories = append(repositories, repositoryDetails.Key+"-cache")
+		default:
+			repositories = append(repositories, repositoryDetails.Key)
+		}
+	}
+	return repositories, nil
+}
+
+// NpmCacheTarballLocator locates the tarball of a package in the local npm cache, and returns its path.
+// If the package's integrity is unknown, an empty integrity can be passed to look the package up by its name and version.
+type NpmCacheTarballLocator func(name, version, integrity string) (string, error)
+
+// NewNpmCacheTarballLocator returns a locator of package tarballs in the npm cache in the given location (as returned by 'npm get cache').
+func NewNpmCacheTarballLocator(cacheLocation string) NpmCacheTarballLocator {
+	cacache := biUtils.NewNpmCacache(cacheLocation)
+	return func(name, version, integrity string) (string, error) {
+		if integrity == "" {
+			info, err := cacache.GetInfo(name + "@" + version)
+			if err != nil {
+				return "", err
+			}
+			integrity = info.Integrity
+		}
+		return cacache.GetTarball(integrity)
+	}
+}
+
+// The prefix of the keys of the npm cache index entries of downloaded tarballs, which is followed by the tarball URL.
+const npmCacheRequestKeyPrefix = "make-fetch-happen:request-cache:"
+
+// An entry of the npm cache index.
+type npmCacheIndexEntry struct {
+	Key       string `json:"key"`
+	Integrity string `json:"integrity"`
+}
+
+// GetNpmCacheIntegrityByResolvedUrl looks up the tarball downloaded from the given URL (the 'resolved' field of the package in 'npm ls' or package-lock.json)
+// in the npm cache in the given location, and returns its integrity. Unlike looking the package up by its name and version,
+// this lookup matches the exact tarball, even if packages with the same name and version are served by several registries.
+func GetNpmCacheIntegrityByResolvedUrl(cacheLocation, resolvedUrl string) (string, error) {
+	key := npmCacheRequestKeyPrefix + resolvedUrl
+	// The index entries are saved in a file whose path is the SHA-256 of the key, for example: index-v5/4e/22/eb8971d3255b...
+	hashBytes := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(hashBytes[:])
+	content, err := os.ReadFile(filepath.Join(cacheLocation, "index-v5", hash[0:2], hash[2:4], hash[4:]))
+	if err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	// Each line holds the hash of the entry and the entry itself, separated by a tab. Later entries override earlier ones.
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		_, entryJson, found := strings.Cut(lines[i], "\t")
+		if !found {
+			continue
+		}
+		var entry npmCacheIndexEntry
+		if err = json.Unmarshal([]byte(entryJson), &entry); err != nil {
+			return "", errorutils.CheckError(err)
+		}
+		if entry.Key == key && entry.Integrity != "" {
+			return entry.Integrity, nil
+		}
+	}
+	return "", errorutils.CheckErrorf("the npm cache index has no integrity for %s", resolvedUrl)
+}
+
+// CalculateFileChecksum calculates the checksums of a local file, such as a package tarball.
+func CalculateFileChecksum(path string) (*buildinfo.Checksum, error) {
+	checksums, err := crypto.GetFileChecksums(path)
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	return &buildinfo.Checksum{Md5: checksums[crypto.MD5], Sha1: checksums[crypto.SHA1], Sha256: checksums[crypto.SHA256]}, nil
+}
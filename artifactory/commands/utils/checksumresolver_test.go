@@ -0,0 +1,194 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jfrog/jfrog-client-go/artifactory"
+	"github.com/jfrog/jfrog-client-go/artifactory/services"
+	"github.com/stretchr/testify/assert"
+)
+
+type aqlMockServicesManager struct {
+	artifactory.EmptyArtifactoryServicesManager
+	aqlResponse  string
+	aqlQueries   []string
+	repositories []services.RepositoryDetails
+	// The filters of the repositories requests, in order.
+	repositoriesFilters []services.RepositoriesFilterParams
+}
+
+func (amsm *aqlMockServicesManager) GetAllRepositoriesFiltered(params services.RepositoriesFilterParams) (*[]services.RepositoryDetails, error) {
+	amsm.repositoriesFilters = append(amsm.repositoriesFilters, params)
+	return &amsm.repositories, nil
+}
+
+func (amsm *aqlMockServicesManager) Aql(query string) (io.ReadCloser, error) {
+	amsm.aqlQueries = append(amsm.aqlQueries, query)
+	return io.NopCloser(strings.NewReader(amsm.aqlResponse)), nil
+}
+
+func TestResolveArtifactChecksum(t *testing.T) {
+	testCases := []struct {
+		name             string
+		aqlResponse      string
+		expectedFound    bool
+		expectedSha1     string
+		expectedFileType string
+	}{
+		{
+			name:             "found",
+			aqlResponse:      `{"results":[{"repo":"npm-remote-cache","path":"send/-","name":"send-0.16.2.tgz","actual_sha1":"sha1-value","actual_md5":"md5-value","sha256":"sha256-value"}]}`,
+			expectedFound:    true,
+			expectedSha1:     "sha1-value",
+			expectedFileType: "tgz",
+		},
+		{
+			name:        "not found",
+			aqlResponse: `{"results":[]}`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			servicesManager := &aqlMockServicesManager{aqlResponse: testCase.aqlResponse}
+			checksum, fileType, err := ResolveArtifactChecksum(servicesManager, "send", "0.16.2")
+			assert.NoError(t, err)
+			assert.Len(t, servicesManager.aqlQueries, 1)
+			assert.Contains(t, servicesManager.aqlQueries[0], `"@npm.name":"send"`)
+			assert.Equal(t, testCase.expectedFileType, fileType)
+			if !testCase.expectedFound {
+				assert.Nil(t, checksum)
+				return
+			}
+			if assert.NotNil(t, checksum) {
+				assert.Equal(t, testCase.expectedSha1, checksum.Sha1)
+				assert.Equal(t, "md5-value", checksum.Md5)
+				assert.Equal(t, "sha256-value", checksum.Sha256)
+			}
+		})
+	}
+}
+
+func TestResolveArtifactInRepositories(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponse: `{"results":[]}`}
+	_, _, _, err := ResolveArtifact(servicesManager, "send", "0.16.2", ArtifactSearchCriteria{Repositories: []string{"proj-npm-local", "npm-remote-cache"}})
+	assert.NoError(t, err)
+	_, _, _, err = ResolveArtifact(servicesManager, "send", "0.16.2", ArtifactSearchCriteria{})
+	assert.NoError(t, err)
+	if assert.Len(t, servicesManager.aqlQueries, 2) {
+		assert.Equal(t, `items.find({"@npm.name":"send","$and":[{"$or":[{"@npm.version":"0.16.2"},{"@npm.version":"v0.16.2"}]},`+
+			`{"$or":[{"repo":"proj-npm-local"},{"repo":"npm-remote-cache"}]}]}).include("name","repo","path","actual_sha1","actual_md5","sha256")`,
+			servicesManager.aqlQueries[0])
+		assert.NotContains(t, servicesManager.aqlQueries[1], `{"repo":`)
+	}
+}
+
+func TestCreateArtifactAqlQueryEscaping(t *testing.T) {
+	query, err := createArtifactAqlQuery(`send"}),items.find({"name":"*`, `0.16.2\`, ArtifactSearchCriteria{Repositories: []string{`npm"-local`}})
+	assert.NoError(t, err)
+	assert.Contains(t, query, `{"@npm.name":"send\"}),items.find({\"name\":\"*",`)
+	assert.Contains(t, query, `{"@npm.version":"0.16.2\\"}`)
+	assert.Contains(t, query, `{"repo":"npm\"-local"}`)
+
+	// The release bundle's name and version are escaped too.
+	query, err = createArtifactAqlQuery("send", "0.16.2", ArtifactSearchCriteria{ReleaseBundle: &ReleaseBundle{Name: `my-bundle","repo":{"$match":"*`, Version: `1.0.0"`}})
+	assert.NoError(t, err)
+	assert.Contains(t, query, `"release_artifact.release.name":"my-bundle\",\"repo\":{\"$match\":\"*","release_artifact.release.version":"1.0.0\""`)
+}
+
+func TestGetProjectRepositories(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{repositories: []services.RepositoryDetails{
+		{Key: "proj-npm-local", Type: "LOCAL"},
+		{Key: "npm-remote", Type: "REMOTE"},
+		{Key: "npm-federated", Type: "FEDERATED"},
+		{Key: "proj-npm", Type: "VIRTUAL"},
+	}}
+	repositories, err := GetProjectRepositories(servicesManager, "proj")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"proj-npm-local", "npm-remote-cache", "npm-federated"}, repositories)
+	assert.Equal(t, []services.RepositoriesFilterParams{{ProjectKey: "proj", PackageType: "npm"}}, servicesManager.repositoriesFilters)
+}
+
+func TestResolveArtifactInReleaseBundle(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponse: `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"bundle-sha1","actual_md5":"bundle-md5"}]}`}
+	criteria := ArtifactSearchCriteria{Repositories: []string{"proj-npm-local"}, ReleaseBundle: &ReleaseBundle{Name: "my-bundle", Version: "1.0.0"}}
+	checksum, fileType, _, err := ResolveArtifact(servicesManager, "send", "0.16.2", criteria)
+	assert.NoError(t, err)
+	assert.Equal(t, "tgz", fileType)
+	if assert.NotNil(t, checksum) {
+		assert.Equal(t, "bundle-sha1", checksum.Sha1)
+	}
+	if assert.Len(t, servicesManager.aqlQueries, 1) {
+		assert.Contains(t, servicesManager.aqlQueries[0],
+			`items.find({"@npm.name":"send","release_artifact.release.name":"my-bundle","release_artifact.release.version":"1.0.0","$and":[`)
+	}
+
+	// Without a release bundle, the release bundle isn't in the query.
+	_, _, _, err = ResolveArtifact(servicesManager, "send", "0.16.2", ArtifactSearchCriteria{})
+	assert.NoError(t, err)
+	if assert.Len(t, servicesManager.aqlQueries, 2) {
+		assert.NotContains(t, servicesManager.aqlQueries[1], "release_artifact")
+	}
+}
+
+func TestResolveArtifactWithProperties(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponse: `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"sha1-value","properties":[` +
+		`{"key":"build.name","value":"send-build"},{"key":"npm.name","value":"send"},{"key":"team","value":"web"},{"key":"team","value":"infra"}]}]}`}
+	checksum, _, properties, err := ResolveArtifact(servicesManager, "send", "0.16.2", ArtifactSearchCriteria{PropertyKeys: []string{"build.name", "team", "build.number"}})
+	assert.NoError(t, err)
+	if assert.NotNil(t, checksum) {
+		assert.Equal(t, "sha1-value", checksum.Sha1)
+	}
+	assert.Equal(t, map[string]string{"build.name": "send-build", "team": "web,infra"}, properties)
+
+	// Without property keys, the properties aren't fetched.
+	_, _, properties, err = ResolveArtifact(servicesManager, "send", "0.16.2", ArtifactSearchCriteria{})
+	assert.NoError(t, err)
+	assert.Nil(t, properties)
+	if assert.Len(t, servicesManager.aqlQueries, 2) {
+		assert.Contains(t, servicesManager.aqlQueries[0], `.include("property.*",`)
+		assert.NotContains(t, servicesManager.aqlQueries[1], "property")
+	}
+}
+
+func TestCalculateFileChecksum(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "package.tgz")
+	assert.NoError(t, os.WriteFile(filePath, []byte("content"), 0644))
+	checksum, err := CalculateFileChecksum(filePath)
+	assert.NoError(t, err)
+	if assert.NotNil(t, checksum) {
+		assert.Equal(t, "040f06fd774092478d450774f5ba30c5da78acc8", checksum.Sha1)
+		assert.Equal(t, "9a0364b9e99bb480dd25e1f0284c8555", checksum.Md5)
+		assert.Equal(t, "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73", checksum.Sha256)
+	}
+
+	_, err = CalculateFileChecksum(filepath.Join(t.TempDir(), "missing.tgz"))
+	assert.Error(t, err)
+}
+
+func TestGetNpmCacheIntegrityByResolvedUrl(t *testing.T) {
+	const resolvedUrl = "https://acme.jfrog.io/artifactory/api/npm/npm-local/send/-/send-0.16.2.tgz"
+	cacheLocation := t.TempDir()
+	// Create the index entry of the tarball, as written by npm. The later entry overrides the earlier one.
+	hashBytes := sha256.Sum256([]byte("make-fetch-happen:request-cache:" + resolvedUrl))
+	hash := hex.EncodeToString(hashBytes[:])
+	indexPath := filepath.Join(cacheLocation, "index-v5", hash[0:2], hash[2:4], hash[4:])
+	assert.NoError(t, os.MkdirAll(filepath.Dir(indexPath), 0755))
+	indexContent := "\n" +
+		`5e1b6cd1b5e0e0fa6a3d0b5a0e4e6f1c1f2e3d4c	{"key":"make-fetch-happen:request-cache:` + resolvedUrl + `","integrity":"sha512-old"}` + "\n" +
+		`8c2b6cd1b5e0e0fa6a3d0b5a0e4e6f1c1f2e3d4c	{"key":"make-fetch-happen:request-cache:` + resolvedUrl + `","integrity":"sha512-new","size":4553}` + "\n"
+	assert.NoError(t, os.WriteFile(indexPath, []byte(indexContent), 0644))
+
+	integrity, err := GetNpmCacheIntegrityByResolvedUrl(cacheLocation, resolvedUrl)
+	assert.NoError(t, err)
+	assert.Equal(t, "sha512-new", integrity)
+
+	// A package with the same name and version, downloaded from another registry, isn't matched.
+	_, err = GetNpmCacheIntegrityByResolvedUrl(cacheLocation, "https://registry.npmjs.org/send/-/send-0.16.2.tgz")
+	assert.Error(t, err)
+}
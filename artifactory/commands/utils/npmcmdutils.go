@@ -25,6 +25,11 @@ const (
 	NpmConfigAuthTokenKey = "_authToken"
 	NpmConfigRegistryKey  = "registry"
 	npmAuthRestApi        = "api/npm/auth"
+
+	// The variable in npm API path templates, which is replaced with the repository name.
+	NpmApiPathRepoVar = "${repo}"
+	// The default path of the npm API of a repository, relative to the Artifactory URL.
+	DefaultNpmApiPathTemplate = "/api/npm/" + NpmApiPathRepoVar
 )
 
 // Constructs npm auth config and registry, manually or by requesting the Artifactory /npm/auth endpoint.
@@ -98,7 +103,15 @@ func getNpmAuthFromArtifactory(artDetails auth.ServiceDetails) (npmAuth string,
 }
 
 func GetNpmRepositoryUrl(repositoryName, artifactoryUrl string) string {
-	return strings.TrimSuffix(artifactoryUrl, "/") + "/api/npm/" + repositoryName
+	return GetNpmRepositoryUrlWithPathTemplate(repositoryName, artifactoryUrl, DefaultNpmApiPathTemplate)
+}
+
+// GetNpmRepositoryUrlWithPathTemplate returns the npm registry URL of the repository, with the npm API path generated from the given template.
+// The template is relative to the Artifactory URL, and the ${repo} variable in it is replaced with the repository name.
+// A custom template is required when Artifactory is fronted by a reverse proxy, which routes the npm API through a different path.
+func GetNpmRepositoryUrlWithPathTemplate(repositoryName, artifactoryUrl, pathTemplate string) string {
+	apiPath := strings.ReplaceAll(pathTemplate, NpmApiPathRepoVar, repositoryName)
+	return strings.TrimSuffix(artifactoryUrl, "/") + "/" + strings.TrimPrefix(apiPath, "/")
 }
 
 // GetNpmAuthKeyValue generates the correct authentication key and value for npm or Yarn, based on the repo URL.
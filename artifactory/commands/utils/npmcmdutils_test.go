@@ -27,6 +27,24 @@ func TestGetRegistry(t *testing.T) {
 	}
 }
 
+func TestGetNpmRepositoryUrlWithPathTemplate(t *testing.T) {
+	testCases := []struct {
+		pathTemplate string
+		url          string
+		expected     string
+	}{
+		{DefaultNpmApiPathTemplate, "http://url/art/", "http://url/art/api/npm/repo"},
+		{"/npm-proxy/${repo}/", "http://url/art", "http://url/art/npm-proxy/repo/"},
+		{"registry/${repo}", "http://url/", "http://url/registry/repo"},
+		{"/npm", "http://url", "http://url/npm"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.pathTemplate, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, GetNpmRepositoryUrlWithPathTemplate("repo", testCase.url, testCase.pathTemplate))
+		})
+	}
+}
+
 type dummyArtifactoryServiceDetails struct {
 	auth.CommonConfigFields
 }
@@ -0,0 +1,98 @@
+package yarn
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jfrog/build-info-go/entities"
+	commandUtils "github.com/jfrog/jfrog-cli-core/v2/artifactory/commands/utils"
+	servicesUtils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+// The results of an AQL query, as returned by Artifactory.
+type aqlDump struct {
+	Results []servicesUtils.ResultItem `json:"results"`
+}
+
+// LoadChecksumsFromAql loads the checksums of npm packages from the results of an AQL query, such as a periodic export of the npm artifacts,
+// so the checksums of these packages aren't looked up in Artifactory while collecting the dependencies.
+// The results must include the artifacts' names, paths and checksums. The package's name and version are taken from the artifact's
+// npm.name and npm.version properties, if included, or otherwise from the artifact's path in the npm layout (<name>/-/<file name>-<version>.tgz).
+// Results whose package can't be identified are skipped. The checksums of a previous build take precedence over the loaded checksums.
+// If the lookup is scoped to the repositories of a project, only the checksums of the artifacts in these repositories are used,
+// and if it's scoped to a release bundle, the loaded checksums aren't used, since the results don't identify the bundle's artifacts.
+// Can be called several times, to load several dumps.
+func (yc *YarnCommand) LoadChecksumsFromAql(reader io.Reader) error {
+	var dump aqlDump
+	if err := json.NewDecoder(reader).Decode(&dump); err != nil {
+		return errorutils.CheckErrorf("failed to parse the AQL results: %s", err.Error())
+	}
+	if yc.preloadedChecksums == nil {
+		yc.preloadedChecksums = make(map[string]*preloadedChecksum)
+	}
+	for _, item := range dump.Results {
+		name, version := getNpmPackageFromAqlResult(item)
+		if name == "" || version == "" {
+			log.Debug("Skipping the AQL result", path.Join(item.Repo, item.Path, item.Name), "since its npm package can't be identified.")
+			continue
+		}
+		id := name + ":" + version
+		yc.preloadedChecksums[id] = &preloadedChecksum{repo: item.Repo, dependency: &entities.Dependency{Id: id, Type: strings.TrimPrefix(path.Ext(item.Name), "."),
+			Checksum: entities.Checksum{Sha1: item.Actual_Sha1, Md5: item.Actual_Md5, Sha256: item.Sha256}}}
+	}
+	return nil
+}
+
+// Returns the name and version of the npm package of the AQL result, or empty strings if they can't be identified.
+func getNpmPackageFromAqlResult(item servicesUtils.ResultItem) (name, version string) {
+	for _, property := range item.Properties {
+		switch property.Key {
+		case "npm.name":
+			name = property.Value
+		case "npm.version":
+			version = strings.TrimPrefix(property.Value, "v")
+		}
+	}
+	if name != "" && version != "" {
+		return
+	}
+	// The npm layout: <name>/-/<file name>-<version>.tgz, where the file name is the name without its scope.
+	name, found := strings.CutSuffix(item.Path, "/-")
+	if !found || !strings.HasSuffix(item.Name, ".tgz") {
+		return "", ""
+	}
+	prefix := path.Base(name) + "-"
+	if !strings.HasPrefix(item.Name, prefix) {
+		return "", ""
+	}
+	return name, strings.TrimSuffix(strings.TrimPrefix(item.Name, prefix), ".tgz")
+}
+
+// A checksum loaded from AQL results, with the repository of its artifact.
+type preloadedChecksum struct {
+	repo       string
+	dependency *entities.Dependency
+}
+
+// Returns the loaded checksums which match the search criteria of the dependencies' artifacts, by the dependencies' IDs.
+func (yc *YarnCommand) getPreloadedChecksums(criteria commandUtils.ArtifactSearchCriteria) map[string]*entities.Dependency {
+	if len(yc.preloadedChecksums) == 0 {
+		return nil
+	}
+	if criteria.ReleaseBundle != nil {
+		log.Debug("The checksums loaded from AQL results aren't used, since the dependencies are searched in a release bundle.")
+		return nil
+	}
+	dependencies := make(map[string]*entities.Dependency)
+	for id, checksum := range yc.preloadedChecksums {
+		if len(criteria.Repositories) == 0 || slices.Contains(criteria.Repositories, checksum.repo) {
+			dependencies[id] = checksum.dependency
+		}
+	}
+	return dependencies
+}
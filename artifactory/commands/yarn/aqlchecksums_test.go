@@ -0,0 +1,69 @@
+package yarn
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jfrog/build-info-go/entities"
+	commandUtils "github.com/jfrog/jfrog-cli-core/v2/artifactory/commands/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+const aqlDumpSample = `{"results":[
+	{"repo":"npm-remote-cache","path":"send/-","name":"send-0.16.2.tgz","actual_sha1":"send-sha1","actual_md5":"send-md5","sha256":"send-sha256"},
+	{"repo":"npm-remote-cache","path":"@jfrog/package/-","name":"package-1.0.0.tgz","actual_sha1":"package-sha1","actual_md5":"package-md5"},
+	{"repo":"npm-local","path":"uploads","name":"debug.tgz","actual_sha1":"debug-sha1","properties":[{"key":"npm.name","value":"debug"},{"key":"npm.version","value":"v4.1.1"}]},
+	{"repo":"npm-local","path":"uploads","name":"unknown.tgz","actual_sha1":"unknown-sha1"}
+]}`
+
+func TestLoadChecksumsFromAql(t *testing.T) {
+	yc := NewYarnCommand()
+	assert.NoError(t, yc.LoadChecksumsFromAql(strings.NewReader(aqlDumpSample)))
+	assert.Equal(t, map[string]*entities.Dependency{
+		"send:0.16.2":          {Id: "send:0.16.2", Type: "tgz", Checksum: entities.Checksum{Sha1: "send-sha1", Md5: "send-md5", Sha256: "send-sha256"}},
+		"@jfrog/package:1.0.0": {Id: "@jfrog/package:1.0.0", Type: "tgz", Checksum: entities.Checksum{Sha1: "package-sha1", Md5: "package-md5"}},
+		"debug:4.1.1":          {Id: "debug:4.1.1", Type: "tgz", Checksum: entities.Checksum{Sha1: "debug-sha1"}},
+	}, yc.getPreloadedChecksums(commandUtils.ArtifactSearchCriteria{}))
+
+	assert.Error(t, yc.LoadChecksumsFromAql(strings.NewReader(`{"results":`)))
+}
+
+func TestGetDependencyInfoWithPreloadedChecksums(t *testing.T) {
+	yc := NewYarnCommand()
+	assert.NoError(t, yc.LoadChecksumsFromAql(strings.NewReader(aqlDumpSample)))
+	previousBuildDependencies := map[string]*entities.Dependency{
+		"send:0.16.2": {Id: "send:0.16.2", Type: "tgz", Checksum: entities.Checksum{Sha1: "previous-sha1"}},
+	}
+	preloadedChecksums := yc.getPreloadedChecksums(commandUtils.ArtifactSearchCriteria{})
+	servicesManager := &aqlMockServicesManager{defaultAqlResponse: `{"results":[{"name":"ms-2.1.2.tgz","actual_sha1":"ms-sha1"}]}`}
+
+	// The previous build takes precedence over the loaded checksums.
+	checksum, _, err := getDependencyInfo("send", "0.16.2", &checksumLookupOptions{previousBuildDependencies: previousBuildDependencies, preloadedChecksums: preloadedChecksums, servicesManager: servicesManager})
+	assert.NoError(t, err)
+	assert.Equal(t, "previous-sha1", checksum.Sha1)
+
+	checksum, fileType, err := getDependencyInfo("@jfrog/package", "1.0.0", &checksumLookupOptions{previousBuildDependencies: previousBuildDependencies, preloadedChecksums: preloadedChecksums, servicesManager: servicesManager})
+	assert.NoError(t, err)
+	assert.Equal(t, "package-sha1", checksum.Sha1)
+	assert.Equal(t, "tgz", fileType)
+	assert.Empty(t, servicesManager.aqlQueries)
+
+	// Dependencies which weren't loaded are looked up in Artifactory.
+	checksum, _, err = getDependencyInfo("ms", "2.1.2", &checksumLookupOptions{previousBuildDependencies: previousBuildDependencies, preloadedChecksums: preloadedChecksums, servicesManager: servicesManager})
+	assert.NoError(t, err)
+	assert.Equal(t, "ms-sha1", checksum.Sha1)
+	assert.Len(t, servicesManager.aqlQueries, 1)
+}
+
+func TestGetPreloadedChecksumsWithSearchCriteria(t *testing.T) {
+	yc := NewYarnCommand()
+	assert.NoError(t, yc.LoadChecksumsFromAql(strings.NewReader(aqlDumpSample)))
+
+	// Only the checksums of the artifacts in the searched repositories are used.
+	preloadedChecksums := yc.getPreloadedChecksums(commandUtils.ArtifactSearchCriteria{Repositories: []string{"npm-local"}})
+	assert.Len(t, preloadedChecksums, 1)
+	assert.Contains(t, preloadedChecksums, "debug:4.1.1")
+
+	// The AQL results don't identify the artifacts of a release bundle, so none of the checksums are used.
+	assert.Empty(t, yc.getPreloadedChecksums(commandUtils.ArtifactSearchCriteria{ReleaseBundle: &commandUtils.ReleaseBundle{Name: "my-bundle", Version: "1.0.0"}}))
+}
@@ -2,20 +2,25 @@ package yarn
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/jfrog/build-info-go/entities"
-	gofrogio "github.com/jfrog/gofrog/io"
 	"github.com/jfrog/jfrog-cli-core/v2/common/format"
 	"github.com/jfrog/jfrog-client-go/artifactory"
 	"github.com/jfrog/jfrog-client-go/artifactory/services"
 	servicesUtils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
 	"io"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jfrog/build-info-go/build"
 	buildUtils "github.com/jfrog/jfrog-cli-core/v2/common/build"
@@ -30,6 +35,7 @@ import (
 	"github.com/jfrog/jfrog-client-go/auth"
 	"github.com/jfrog/jfrog-client-go/utils/errorutils"
 	"github.com/jfrog/jfrog-client-go/utils/log"
+	"golang.org/x/exp/maps"
 )
 
 const (
@@ -46,21 +52,82 @@ const (
 )
 
 type YarnCommand struct {
-	executablePath     string
-	workingDirectory   string
-	registry           string
-	npmAuthIdent       string
-	npmAuthToken       string
-	repo               string
-	collectBuildInfo   bool
-	configFilePath     string
-	yarnArgs           []string
-	threads            int
-	serverDetails      *config.ServerDetails
+	executablePath   string
+	workingDirectory string
+	registry         string
+	npmAuthIdent     string
+	npmAuthToken     string
+	repo             string
+	collectBuildInfo bool
+	configFilePath   string
+	yarnArgs         []string
+	threads          int
+	serverDetails    *config.ServerDetails
+	// The format of the config file. If empty, the format is detected by the file's extension or content.
+	configFormat project.ConfigType
+	// If set, override the resolver repository and server ID of the config file.
+	repoOverride       string
+	serverIdOverride   string
 	buildConfiguration *buildUtils.BuildConfiguration
 	buildInfoModule    *build.YarnModule
+	// Called after the checksum of each dependency is looked up, with whether it was found or not.
+	onDependencyResolved func(name, version string, found bool)
+	// If true, the checksums of dependencies which are not found in Artifactory are calculated from their archives in the local Yarn cache.
+	localChecksumFallback bool
+	// The number of the build whose dependencies' checksums are reused. If empty, the latest build is used.
+	previousBuildNumber string
+	// The user-agent of the requests sent to Artifactory while collecting the dependencies' checksums.
+	userAgent string
+	// If set, the dependencies' checksums are searched only in the repositories of the JFrog Project with this key.
+	projectKey string
+	// The retries of the failed requests sent to Artifactory while looking up the dependencies' checksums. If nil, the default retries are used.
+	lookupRetryConfig *coreutils.RetryConfig
+	// The HTTP transport of the requests sent to Artifactory while looking up the dependencies' checksums. If nil, the default transport is used.
+	lookupTransport http.RoundTripper
+	// If set, dependencies without a checksum of this type are considered missing.
+	requiredChecksumType ChecksumType
+	// If set, the dependencies' checksums are searched only in the artifacts of this release bundle version.
+	releaseBundle *commandUtils.ReleaseBundle
+	// If set, the dependencies missing in Artifactory are reported to this writer, in the missing dependencies format, instead of to the log.
+	missingDepsSink   io.Writer
+	missingDepsFormat MissingDependenciesFormat
+	// The durations of the dependencies' checksums lookups in the last run.
+	lookupDurations *dependencyLookupDurations
+	// The checksums loaded from AQL results, by the dependencies' IDs.
+	preloadedChecksums map[string]*preloadedChecksum
+	// Additional Artifactory instances, in which the dependencies' checksums are looked up in order, if they aren't found in the primary one.
+	secondaryChecksumServers []*config.ServerDetails
+	// The keys of the Artifactory properties collected from the dependencies' artifacts.
+	artifactPropertyKeys []string
+	// The properties collected from the dependencies' artifacts in the last run.
+	artifactProperties *dependencyArtifactProperties
+	// If set, the type of all the dependencies in the build-info, instead of the extensions of their artifacts.
+	forcedDependencyType string
 }
 
+// MissingDependenciesFormat is the format in which the dependencies missing in Artifactory are reported.
+type MissingDependenciesFormat string
+
+const (
+	// The IDs of the missing dependencies, one per line.
+	MissingDependenciesText MissingDependenciesFormat = "text"
+	// A JSON array of the missing dependencies, each with its name and version.
+	MissingDependenciesJson MissingDependenciesFormat = "json"
+)
+
+type missingDependency struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ChecksumType is a type of checksum of the dependencies in the build-info.
+type ChecksumType string
+
+const (
+	Sha1ChecksumType   ChecksumType = "sha1"
+	Sha256ChecksumType ChecksumType = "sha256"
+)
+
 func NewYarnCommand() *YarnCommand {
 	return &YarnCommand{}
 }
@@ -70,11 +137,144 @@ func (yc *YarnCommand) SetConfigFilePath(configFilePath string) *YarnCommand {
 	return yc
 }
 
+// SetConfigFormat sets the format of the config file, project.YAML or project.JSON.
+// By default, the format is detected by the file's extension, or by its content if the extension is neither '.json', '.yaml' nor '.yml'.
+func (yc *YarnCommand) SetConfigFormat(configFormat project.ConfigType) *YarnCommand {
+	yc.configFormat = configFormat
+	return yc
+}
+
 func (yc *YarnCommand) SetArgs(args []string) *YarnCommand {
 	yc.yarnArgs = args
 	return yc
 }
 
+// SetOnDependencyResolved sets a function to be called after the checksum of each dependency is looked up while collecting the build-info,
+// for example to report the collection progress. The found argument indicates whether the dependency's checksum was found.
+// Although the dependencies are looked up concurrently, the calls to the function are never concurrent.
+func (yc *YarnCommand) SetOnDependencyResolved(onDependencyResolved func(name, version string, found bool)) *YarnCommand {
+	yc.onDependencyResolved = onDependencyResolved
+	return yc
+}
+
+// SetLocalChecksumFallback makes the command calculate the checksums of dependencies which could not be found in Artifactory
+// from their archives in the local Yarn cache, if they exist there, instead of excluding them from the build-info.
+// Yarn caches the packages as zip archives rather than as their tarballs, so the type of these dependencies is zip.
+func (yc *YarnCommand) SetLocalChecksumFallback(localChecksumFallback bool) *YarnCommand {
+	yc.localChecksumFallback = localChecksumFallback
+	return yc
+}
+
+// SetPreviousBuildNumber sets the number of the previous build whose dependencies' checksums are reused, to reduce the requests to Artifactory.
+// This allows resolving the checksums as they were in a specific build. By default, the latest build with the same build name is used.
+func (yc *YarnCommand) SetPreviousBuildNumber(previousBuildNumber string) *YarnCommand {
+	yc.previousBuildNumber = previousBuildNumber
+	return yc
+}
+
+// SetUserAgent sets the user-agent of the requests sent to Artifactory while collecting the dependencies' checksums.
+// By default, the jfrog-cli-core name and version are used.
+func (yc *YarnCommand) SetUserAgent(userAgent string) *YarnCommand {
+	yc.userAgent = userAgent
+	return yc
+}
+
+// SetProjectKey scopes the search of the dependencies' checksums in Artifactory to the repositories of the JFrog Project with the given key.
+// By default, all the repositories are searched.
+func (yc *YarnCommand) SetProjectKey(projectKey string) *YarnCommand {
+	yc.projectKey = projectKey
+	return yc
+}
+
+// SetDependencyLookupRetryConfig sets the retries of the failed requests sent to Artifactory while looking up the dependencies' checksums,
+// independently of the retries of the extractors downloads. By default, the HTTP client's default retries are used.
+func (yc *YarnCommand) SetDependencyLookupRetryConfig(lookupRetryConfig *coreutils.RetryConfig) *YarnCommand {
+	yc.lookupRetryConfig = lookupRetryConfig
+	return yc
+}
+
+// SetDependencyLookupTransport sets the HTTP transport of the requests sent to Artifactory while looking up the dependencies' checksums,
+// for example, a mock transport in tests. By default, the HTTP client's default transport is used.
+func (yc *YarnCommand) SetDependencyLookupTransport(lookupTransport http.RoundTripper) *YarnCommand {
+	yc.lookupTransport = lookupTransport
+	return yc
+}
+
+// AddSecondaryChecksumServer adds an Artifactory instance, in which the dependencies' checksums are looked up if they aren't found
+// in the command's server, for example in federated topologies. The secondary servers are queried in the order they were added,
+// with the same project key and release bundle filters, retries and transport as the command's server.
+func (yc *YarnCommand) AddSecondaryChecksumServer(serverDetails *config.ServerDetails) *YarnCommand {
+	yc.secondaryChecksumServers = append(yc.secondaryChecksumServers, serverDetails)
+	return yc
+}
+
+// SetCollectArtifactProperties makes the command collect the Artifactory properties with the given keys (such as build.name, of the build
+// which produced the dependency) from the artifacts of the dependencies, while looking up their checksums.
+// The collected properties are returned by GetDependencyArtifactProperties.
+func (yc *YarnCommand) SetCollectArtifactProperties(propertyKeys []string) *YarnCommand {
+	yc.artifactPropertyKeys = propertyKeys
+	return yc
+}
+
+// SetRequiredChecksumType makes the command consider dependencies without a checksum of the given type (sha1 or sha256) as missing,
+// and exclude them from the build-info, for example to comply with a SHA-256 checksums policy. By default, any checksum is accepted.
+func (yc *YarnCommand) SetRequiredChecksumType(requiredChecksumType ChecksumType) *YarnCommand {
+	yc.requiredChecksumType = requiredChecksumType
+	return yc
+}
+
+// SetReleaseBundle makes the command resolve the dependencies' checksums from the artifacts of the given release bundle version,
+// instead of from all the artifacts in Artifactory, which ties the build-info to an immutable release bundle.
+// Dependencies which aren't in the release bundle are considered missing.
+func (yc *YarnCommand) SetReleaseBundle(name, version string) *YarnCommand {
+	yc.releaseBundle = &commandUtils.ReleaseBundle{Name: name, Version: version}
+	return yc
+}
+
+// SetMissingDependenciesSink makes the command report the dependencies which could not be found in Artifactory to the given writer,
+// in the given format (text or JSON), instead of logging them. This allows CI systems to process the missing dependencies.
+// In the JSON format, an empty array is written if no dependencies are missing.
+func (yc *YarnCommand) SetMissingDependenciesSink(sink io.Writer, format MissingDependenciesFormat) *YarnCommand {
+	yc.missingDepsSink = sink
+	yc.missingDepsFormat = format
+	return yc
+}
+
+// SetRepoOverride sets the resolver repository, which takes precedence over the resolver repository of the config file.
+// If both the repository and the server are overridden, the config file isn't read.
+func (yc *YarnCommand) SetRepoOverride(repo string) *YarnCommand {
+	yc.repoOverride = repo
+	return yc
+}
+
+// SetServerOverride sets the ID of the resolver server, which takes precedence over the resolver server of the config file.
+// The server must exist in the stored server configurations.
+func (yc *YarnCommand) SetServerOverride(serverId string) *YarnCommand {
+	yc.serverIdOverride = serverId
+	return yc
+}
+
+// GetDependencyLookupDurations returns the time it took to look up the checksum of each dependency in the last run, by dependency ID,
+// including the retries of failed lookups. Dependencies found in the previous build are looked up quickly, without querying Artifactory.
+// Returns nil if no dependencies were looked up.
+func (yc *YarnCommand) GetDependencyLookupDurations() map[string]time.Duration {
+	return yc.lookupDurations.get()
+}
+
+// SetForceDependencyType sets the type of all the dependencies in the build-info (for example, 'npm'), regardless of their files' extensions.
+// By default, the type of each dependency is the extension of its artifact in Artifactory, such as 'tgz'.
+func (yc *YarnCommand) SetForceDependencyType(forcedDependencyType string) *YarnCommand {
+	yc.forcedDependencyType = forcedDependencyType
+	return yc
+}
+
+// GetDependencyArtifactProperties returns the properties collected from the dependencies' artifacts in the last run, by dependency ID.
+// The build-info dependencies have no properties, so the collected properties aren't saved in the build-info.
+// Only dependencies found in Artifactory, with at least one of the properties set by SetCollectArtifactProperties, are included.
+func (yc *YarnCommand) GetDependencyArtifactProperties() map[string]map[string]string {
+	return yc.artifactProperties.get()
+}
+
 func (yc *YarnCommand) Run() (err error) {
 	log.Info("Running Yarn...")
 	if err = yc.validateSupportedCommand(); err != nil {
@@ -97,12 +297,14 @@ func (yc *YarnCommand) Run() (err error) {
 
 	var missingDepsChan chan string
 	var missingDependencies []string
+	missingDepsCollected := make(chan struct{})
 	if yc.collectBuildInfo {
 		missingDepsChan, err = yc.prepareBuildInfo()
 		if err != nil {
 			return
 		}
 		go func() {
+			defer close(missingDepsCollected)
 			for depId := range missingDepsChan {
 				missingDependencies = append(missingDependencies, depId)
 			}
@@ -125,7 +327,10 @@ func (yc *YarnCommand) Run() (err error) {
 
 	if yc.collectBuildInfo {
 		close(missingDepsChan)
-		printMissingDependencies(missingDependencies)
+		<-missingDepsCollected
+		if err = yc.reportMissingDependencies(missingDependencies); err != nil {
+			return errors.Join(err, RestoreConfigurationsFromBackup(backupEnvMap, restoreYarnrcFunc))
+		}
 	}
 
 	if err = RestoreConfigurationsFromBackup(backupEnvMap, restoreYarnrcFunc); err != nil {
@@ -161,9 +366,21 @@ func (yc *YarnCommand) validateSupportedCommand() error {
 	return nil
 }
 
-func (yc *YarnCommand) readConfigFile() error {
+func (yc *YarnCommand) readConfigFile() (err error) {
+	if yc.repoOverride != "" && yc.serverIdOverride != "" {
+		log.Debug("The resolver repository and server are set explicitly. Skipping the config file.")
+		yc.repo = yc.repoOverride
+		yc.serverDetails, err = config.GetSpecificConfig(yc.serverIdOverride, false, true)
+		return
+	}
 	log.Debug("Preparing to read the config file", yc.configFilePath)
-	vConfig, err := project.ReadConfigFile(yc.configFilePath, project.YAML)
+	configFormat := yc.configFormat
+	if configFormat == "" {
+		if configFormat, err = project.DetectConfigType(yc.configFilePath); err != nil {
+			return err
+		}
+	}
+	vConfig, err := project.ReadConfigFile(yc.configFilePath, configFormat)
 	if err != nil {
 		return err
 	}
@@ -174,6 +391,13 @@ func (yc *YarnCommand) readConfigFile() error {
 		return err
 	}
 	yc.repo = resolverParams.TargetRepo()
+	if yc.repoOverride != "" {
+		yc.repo = yc.repoOverride
+	}
+	if yc.serverIdOverride != "" {
+		yc.serverDetails, err = config.GetSpecificConfig(yc.serverIdOverride, false, true)
+		return
+	}
 	yc.serverDetails, err = resolverParams.ServerDetails()
 	return err
 }
@@ -222,29 +446,106 @@ func (yc *YarnCommand) preparePrerequisites() error {
 	return err
 }
 
+// Creates the services manager used to look up the dependencies' checksums in the given Artifactory instance.
+func (yc *YarnCommand) createLookupServicesManager(serverDetails *config.ServerDetails) (artifactory.ArtifactoryServicesManager, error) {
+	maxRetries, waitMs := yc.lookupRetryConfig.GetRetries()
+	var httpClient *http.Client
+	if yc.lookupTransport != nil {
+		httpClient = &http.Client{Transport: yc.lookupTransport}
+	} else {
+		// Supports a SOCKS5 proxy set in the ALL_PROXY environment variable.
+		var err error
+		if httpClient, err = utils.CreateAllProxyHttpClientForServer(serverDetails, 0); err != nil {
+			return nil, err
+		}
+	}
+	return utils.CreateServiceManagerWithHttpClient(context.Background(), serverDetails, httpClient, maxRetries, waitMs, 0, yc.userAgent)
+}
+
+// Creates the services managers used to look up the dependencies' checksums in the secondary Artifactory instances, in order.
+func (yc *YarnCommand) createSecondaryLookupServicesManagers() ([]artifactory.ArtifactoryServicesManager, error) {
+	var servicesManagers []artifactory.ArtifactoryServicesManager
+	for _, serverDetails := range yc.secondaryChecksumServers {
+		servicesManager, err := yc.createLookupServicesManager(serverDetails)
+		if err != nil {
+			return nil, err
+		}
+		servicesManagers = append(servicesManagers, servicesManager)
+	}
+	return servicesManagers, nil
+}
+
 func (yc *YarnCommand) prepareBuildInfo() (missingDepsChan chan string, err error) {
 	log.Info("Preparing for dependencies information collection... For the first run of the build, the dependencies collection may take a few minutes. Subsequent runs should be faster.")
-	servicesManager, err := utils.CreateServiceManager(yc.serverDetails, -1, 0, false)
+	if yc.requiredChecksumType != "" && yc.requiredChecksumType != Sha1ChecksumType && yc.requiredChecksumType != Sha256ChecksumType {
+		err = errorutils.CheckErrorf("unsupported required checksum type '%s'. The supported types are %s and %s", yc.requiredChecksumType, Sha1ChecksumType, Sha256ChecksumType)
+		return
+	}
+	servicesManager, err := yc.createLookupServicesManager(yc.serverDetails)
+	if err != nil {
+		return
+	}
+	secondaryServicesManagers, err := yc.createSecondaryLookupServicesManagers()
 	if err != nil {
 		return
 	}
 
-	// Collect checksums from last build to decrease requests to Artifactory
+	// Collect checksums from a previous build to decrease requests to Artifactory
 	buildName, err := yc.buildConfiguration.GetBuildName()
 	if err != nil {
 		return
 	}
-	previousBuildDependencies, err := getDependenciesFromLatestBuild(servicesManager, buildName)
+	previousBuildDependencies, err := getDependenciesFromPreviousBuild(servicesManager, buildName, yc.previousBuildNumber)
+	if err != nil {
+		return
+	}
+	searchCriteria, err := yc.createArtifactSearchCriteria(servicesManager)
 	if err != nil {
 		return
 	}
+	var locateCachedPackage cachedPackageLocator
+	if yc.localChecksumFallback {
+		locateCachedPackage = yc.createYarnCacheLocator()
+	}
 	missingDepsChan = make(chan string)
-	collectChecksumsFunc := createCollectChecksumsFunc(previousBuildDependencies, servicesManager, missingDepsChan)
+	yc.lookupDurations = &dependencyLookupDurations{}
+	yc.artifactProperties = &dependencyArtifactProperties{keys: yc.artifactPropertyKeys}
+	collectChecksumsFunc := createCollectChecksumsFunc(&checksumLookupOptions{
+		previousBuildDependencies: previousBuildDependencies,
+		preloadedChecksums:        yc.getPreloadedChecksums(searchCriteria),
+		servicesManager:           servicesManager,
+		secondaryServicesManagers: secondaryServicesManagers,
+		searchCriteria:            searchCriteria,
+		requiredChecksumType:      yc.requiredChecksumType,
+		onDependencyResolved:      yc.onDependencyResolved,
+		locateCachedPackage:       locateCachedPackage,
+		lookupDurations:           yc.lookupDurations,
+		artifactProperties:        yc.artifactProperties,
+	}, missingDepsChan)
+	if yc.forcedDependencyType != "" {
+		collectChecksumsFunc = forceDependencyType(collectChecksumsFunc, yc.forcedDependencyType)
+	}
 	yc.buildInfoModule.SetTraverseDependenciesFunc(collectChecksumsFunc)
 	yc.buildInfoModule.SetThreads(yc.threads)
 	return
 }
 
+// Returns the criteria of the dependencies' artifacts. The repositories of the project are resolved once per run, in the primary Artifactory instance,
+// and searched in the secondary instances too, where federated repositories have the same keys.
+func (yc *YarnCommand) createArtifactSearchCriteria(servicesManager artifactory.ArtifactoryServicesManager) (criteria commandUtils.ArtifactSearchCriteria, err error) {
+	criteria.ReleaseBundle = yc.releaseBundle
+	if yc.projectKey == "" {
+		return
+	}
+	if criteria.Repositories, err = commandUtils.GetProjectRepositories(servicesManager, yc.projectKey); err != nil {
+		return
+	}
+	if len(criteria.Repositories) == 0 {
+		err = errorutils.CheckErrorf("no npm repositories were found in the JFrog Project '%s'", yc.projectKey)
+	}
+	return
+}
+
 func (yc *YarnCommand) setYarnExecutable() error {
 	yarnExecPath, err := exec.LookPath("yarn")
 	if err != nil {
@@ -385,68 +686,102 @@ func extractAuthValFromNpmAuth(npmAuth string) (authIndent, authToken string, er
 	return "", "", errorutils.CheckErrorf("failed while retrieving npm auth details from Artifactory")
 }
 
-type aqlResult struct {
-	Results []*servicesUtils.ResultItem `json:"results,omitempty"`
-}
-
-func getDependenciesFromLatestBuild(servicesManager artifactory.ArtifactoryServicesManager, buildName string) (map[string]*entities.Dependency, error) {
+// Returns the dependencies of the build with the given name and number, by their IDs. If the build number is empty, the latest build is used.
+func getDependenciesFromPreviousBuild(servicesManager artifactory.ArtifactoryServicesManager, buildName, buildNumber string) (map[string]*entities.Dependency, error) {
+	if buildNumber == "" {
+		buildNumber = servicesUtils.LatestBuildNumberKey
+	}
 	buildDependencies := make(map[string]*entities.Dependency)
-	previousBuild, found, err := servicesManager.GetBuildInfo(services.BuildInfoParams{BuildName: buildName, BuildNumber: servicesUtils.LatestBuildNumberKey})
+	previousBuild, found, err := servicesManager.GetBuildInfo(services.BuildInfoParams{BuildName: buildName, BuildNumber: buildNumber})
 	if err != nil || !found {
 		return buildDependencies, err
 	}
 	for _, module := range previousBuild.BuildInfo.Modules {
 		for _, dependency := range module.Dependencies {
-			buildDependencies[dependency.Id] = &entities.Dependency{Id: dependency.Id, Type: dependency.Type,
-				Checksum: entities.Checksum{Md5: dependency.Md5, Sha1: dependency.Sha1}}
+			buildDependencies[dependency.Id] = &entities.Dependency{Id: dependency.Id, Type: dependency.Type, Checksum: dependency.Checksum}
 		}
 	}
 	return buildDependencies, nil
 }
 
-// Get dependency's checksum and type.
-func getDependencyInfo(name, ver string, previousBuildDependencies map[string]*entities.Dependency,
-	servicesManager artifactory.ArtifactoryServicesManager) (checksum entities.Checksum, fileType string, err error) {
+// Get dependency's checksum and type, according to the lookup options.
+// If the dependency isn't found in Artifactory, it's searched in the secondary Artifactory instances, in order.
+// If it isn't found in any of them and a locator of the local Yarn cache is provided, the checksum is calculated from the cached archive.
+// If properties to collect are given, they're collected from the dependency's artifact, if it's found in Artifactory,
+// even if the dependency's checksum is taken from the previous build.
+func getDependencyInfo(name, ver string, options *checksumLookupOptions) (checksum entities.Checksum, fileType string, err error) {
 	id := name + ":" + ver
-	if dep, ok := previousBuildDependencies[id]; ok {
-		// Get checksum from previous build.
+	dep, ok := options.previousBuildDependencies[id]
+	if !ok {
+		dep, ok = options.preloadedChecksums[id]
+	}
+	if ok {
+		// Get checksum from previous build, or from the loaded AQL results.
 		checksum = dep.Checksum
 		fileType = dep.Type
+		if len(options.artifactProperties.getKeys()) > 0 {
+			// The previous build doesn't include the artifacts' properties, so they're still collected from Artifactory.
+			var properties map[string]string
+			if _, _, properties, err = resolveDependencyInArtifactory(name, ver, options); err != nil {
+				return
+			}
+			options.artifactProperties.record(id, properties)
+		}
 		return
 	}
 
 	// Get info from Artifactory.
-	log.Debug("Fetching checksums for", id)
-	var stream io.ReadCloser
-	stream, err = servicesManager.Aql(servicesUtils.CreateAqlQueryForYarn(name, ver))
+	resolvedChecksum, fileType, properties, err := resolveDependencyInArtifactory(name, ver, options)
 	if err != nil {
 		return
 	}
-	defer gofrogio.Close(stream, &err)
-	var result []byte
-	result, err = io.ReadAll(stream)
-	if err != nil {
-		return
+	options.artifactProperties.record(id, properties)
+	if resolvedChecksum == nil {
+		// The AQL search returns no results for a dependency which doesn't exist, so it's missing rather than failing the collection.
+		log.Debug(id, "was not found in Artifactory.")
+	}
+	if resolvedChecksum == nil && options.locateCachedPackage != nil {
+		return getDependencyInfoFromYarnCache(name, ver, options.locateCachedPackage)
 	}
-	parsedResult := new(aqlResult)
-	if err = json.Unmarshal(result, parsedResult); err != nil {
-		return entities.Checksum{}, "", errorutils.CheckError(err)
+	if resolvedChecksum != nil {
+		checksum = *resolvedChecksum
 	}
-	if len(parsedResult.Results) == 0 {
-		log.Debug(id, "could not be found in Artifactory.")
+	return
+}
+
+// Looks the dependency's artifact up in the primary Artifactory instance, and then in the secondary ones, in order, until it's found.
+// A secondary instance which fails to respond is skipped, so the dependency is still looked up in the next ones.
+func resolveDependencyInArtifactory(name, ver string, options *checksumLookupOptions) (checksum *entities.Checksum, fileType string, properties map[string]string, err error) {
+	id := name + ":" + ver
+	criteria := options.searchCriteria
+	criteria.PropertyKeys = options.artifactProperties.getKeys()
+	if checksum, fileType, properties, err = commandUtils.ResolveArtifact(options.servicesManager, name, ver, criteria); err != nil {
 		return
 	}
-	if i := strings.LastIndex(parsedResult.Results[0].Name, "."); i != -1 {
-		fileType = parsedResult.Results[0].Name[i+1:]
+	for i := 0; checksum == nil && i < len(options.secondaryServicesManagers); i++ {
+		log.Debug(id, "was not found in Artifactory. Looking it up in secondary Artifactory instance", strconv.Itoa(i+1)+"...")
+		var secondaryErr error
+		if checksum, fileType, properties, secondaryErr = commandUtils.ResolveArtifact(options.secondaryServicesManagers[i], name, ver, criteria); secondaryErr != nil {
+			log.Warn("Failed to look", id, "up in secondary Artifactory instance", strconv.Itoa(i+1)+":", secondaryErr.Error())
+		}
 	}
-	log.Debug(id, "was found in Artifactory. Name:", parsedResult.Results[0].Name,
-		"SHA-1:", parsedResult.Results[0].Actual_Sha1,
-		"MD5:", parsedResult.Results[0].Actual_Md5)
-
-	checksum = entities.Checksum{Sha1: parsedResult.Results[0].Actual_Sha1, Md5: parsedResult.Results[0].Actual_Md5, Sha256: parsedResult.Results[0].Sha256}
 	return
 }
 
+func getDependencyInfoFromYarnCache(name, ver string, locateCachedPackage cachedPackageLocator) (checksum entities.Checksum, fileType string, err error) {
+	archivePath, err := locateCachedPackage(name, ver)
+	if err != nil {
+		log.Debug(name+":"+ver, "could not be found in the local Yarn cache:", err.Error())
+		return checksum, "", nil
+	}
+	calculatedChecksum, err := commandUtils.CalculateFileChecksum(archivePath)
+	if err != nil {
+		return
+	}
+	log.Debug(name+":"+ver, "was found in the local Yarn cache. SHA-1:", calculatedChecksum.Sha1)
+	return *calculatedChecksum, strings.TrimPrefix(filepath.Ext(archivePath), "."), nil
+}
+
 func extractYarnOptionsFromArgs(args []string) (threads int, detailedSummary, xrayScan bool, scanOutputFormat format.OutputFormat, cleanArgs []string, buildConfig *buildUtils.BuildConfiguration, err error) {
 	threads = 3
 	// Extract threads information from the args.
@@ -466,6 +801,33 @@ func extractYarnOptionsFromArgs(args []string) (threads int, detailedSummary, xr
 	return
 }
 
+// Reports the dependencies missing in Artifactory to the missing dependencies sink, or logs them if no sink is set.
+func (yc *YarnCommand) reportMissingDependencies(missingDependencies []string) error {
+	if yc.missingDepsSink == nil {
+		printMissingDependencies(missingDependencies)
+		return nil
+	}
+	sort.Strings(missingDependencies)
+	switch yc.missingDepsFormat {
+	case MissingDependenciesJson:
+		missingDeps := make([]missingDependency, 0, len(missingDependencies))
+		for _, depId := range missingDependencies {
+			splitDepId := strings.SplitN(depId, ":", 2)
+			missingDeps = append(missingDeps, missingDependency{Name: splitDepId[0], Version: splitDepId[1]})
+		}
+		return errorutils.CheckError(json.NewEncoder(yc.missingDepsSink).Encode(missingDeps))
+	case MissingDependenciesText, "":
+		for _, depId := range missingDependencies {
+			if _, err := fmt.Fprintln(yc.missingDepsSink, depId); err != nil {
+				return errorutils.CheckError(err)
+			}
+		}
+		return nil
+	default:
+		return errorutils.CheckErrorf("unsupported missing dependencies format '%s'", yc.missingDepsFormat)
+	}
+}
+
 func printMissingDependencies(missingDependencies []string) {
 	if len(missingDependencies) == 0 {
 		return
@@ -475,18 +837,168 @@ func printMissingDependencies(missingDependencies []string) {
 		"Deleting the local cache will force populating Artifactory with these dependencies.")
 }
 
-func createCollectChecksumsFunc(previousBuildDependencies map[string]*entities.Dependency, servicesManager artifactory.ArtifactoryServicesManager, missingDepsChan chan string) func(dependency *entities.Dependency) (bool, error) {
+// Returns true if the checksum includes the given type of checksum. Any checksum is accepted if the type is empty.
+func hasChecksumOfType(checksum entities.Checksum, checksumType ChecksumType) bool {
+	switch checksumType {
+	case Sha1ChecksumType:
+		return checksum.Sha1 != ""
+	case Sha256ChecksumType:
+		return checksum.Sha256 != ""
+	default:
+		return true
+	}
+}
+
+// Returns the dependencies of the previous build which include the given type of checksum, so the other dependencies are looked up in Artifactory
+// rather than being considered missing. For example, a previous build recorded without SHA-256 checksums.
+func filterDependenciesWithChecksumType(dependencies map[string]*entities.Dependency, checksumType ChecksumType) map[string]*entities.Dependency {
+	if checksumType == "" {
+		return dependencies
+	}
+	filteredDependencies := make(map[string]*entities.Dependency)
+	for id, dependency := range dependencies {
+		if hasChecksumOfType(dependency.Checksum, checksumType) {
+			filteredDependencies[id] = dependency
+		}
+	}
+	return filteredDependencies
+}
+
+// Records the durations of the dependencies' checksums lookups, which run concurrently. A nil recorder records nothing.
+type dependencyLookupDurations struct {
+	mutex     sync.Mutex
+	durations map[string]time.Duration
+}
+
+func (dld *dependencyLookupDurations) record(dependencyId string, duration time.Duration) {
+	if dld == nil {
+		return
+	}
+	dld.mutex.Lock()
+	defer dld.mutex.Unlock()
+	if dld.durations == nil {
+		dld.durations = make(map[string]time.Duration)
+	}
+	dld.durations[dependencyId] = duration
+}
+
+// Returns a copy of the recorded durations, by dependency ID.
+func (dld *dependencyLookupDurations) get() map[string]time.Duration {
+	if dld == nil {
+		return nil
+	}
+	dld.mutex.Lock()
+	defer dld.mutex.Unlock()
+	return maps.Clone(dld.durations)
+}
+
+// Wraps the function which collects the dependencies' checksums, so the collected dependencies get the given type instead of their files' extensions.
+func forceDependencyType(collectChecksumsFunc func(dependency *entities.Dependency) (bool, error), dependencyType string) func(dependency *entities.Dependency) (bool, error) {
+	return func(dependency *entities.Dependency) (bool, error) {
+		found, err := collectChecksumsFunc(dependency)
+		if found {
+			dependency.Type = dependencyType
+		}
+		return found, err
+	}
+}
+
+// The properties collected from the dependencies' artifacts, which are recorded concurrently.
+type dependencyArtifactProperties struct {
+	// The keys of the collected properties. If empty, no properties are collected.
+	keys       []string
+	mutex      sync.Mutex
+	properties map[string]map[string]string
+}
+
+func (dap *dependencyArtifactProperties) getKeys() []string {
+	if dap == nil {
+		return nil
+	}
+	return dap.keys
+}
+
+func (dap *dependencyArtifactProperties) record(dependencyId string, properties map[string]string) {
+	if dap == nil || len(properties) == 0 {
+		return
+	}
+	dap.mutex.Lock()
+	defer dap.mutex.Unlock()
+	if dap.properties == nil {
+		dap.properties = make(map[string]map[string]string)
+	}
+	dap.properties[dependencyId] = properties
+}
+
+// Returns a copy of the recorded properties, by dependency ID.
+func (dap *dependencyArtifactProperties) get() map[string]map[string]string {
+	if dap == nil {
+		return nil
+	}
+	dap.mutex.Lock()
+	defer dap.mutex.Unlock()
+	return maps.Clone(dap.properties)
+}
+
+// The options of the dependencies' checksums lookups of a run, which are shared by all the lookups.
+type checksumLookupOptions struct {
+	// The dependencies of the previous build, by their IDs. Their checksums are used without looking them up in Artifactory.
+	previousBuildDependencies map[string]*entities.Dependency
+	// The checksums loaded from AQL results, by the dependencies' IDs. The previous build takes precedence over them.
+	preloadedChecksums map[string]*entities.Dependency
+	servicesManager    artifactory.ArtifactoryServicesManager
+	// Additional Artifactory instances, in which the dependencies are looked up in order, if they aren't found in the primary one.
+	secondaryServicesManagers []artifactory.ArtifactoryServicesManager
+	// Narrows down the search of the dependencies' artifacts, such as to the repositories of a project.
+	searchCriteria commandUtils.ArtifactSearchCriteria
+	// If set, dependencies without a checksum of this type are considered missing.
+	requiredChecksumType ChecksumType
+	// Called after the checksum of each dependency is looked up, with whether it was found or not.
+	onDependencyResolved func(name, version string, found bool)
+	// If set, the checksums of the dependencies which aren't found in Artifactory are calculated from their archives in the local Yarn cache.
+	locateCachedPackage cachedPackageLocator
+	// If set, the durations of the lookups are recorded to it.
+	lookupDurations *dependencyLookupDurations
+	// If set, the properties of the dependencies' artifacts are collected to it.
+	artifactProperties *dependencyArtifactProperties
+}
+
+func createCollectChecksumsFunc(options *checksumLookupOptions, missingDepsChan chan string) func(dependency *entities.Dependency) (bool, error) {
+	lookupOptions := *options
+	lookupOptions.previousBuildDependencies = filterDependenciesWithChecksumType(options.previousBuildDependencies, options.requiredChecksumType)
+	lookupOptions.preloadedChecksums = filterDependenciesWithChecksumType(options.preloadedChecksums, options.requiredChecksumType)
+	requiredChecksumType := options.requiredChecksumType
+	onDependencyResolved := options.onDependencyResolved
+	// The returned function runs concurrently, so the calls to onDependencyResolved are synchronized.
+	var onDependencyResolvedMutex sync.Mutex
+	notifyResolved := func(name, ver string, found bool) {
+		if onDependencyResolved == nil {
+			return
+		}
+		onDependencyResolvedMutex.Lock()
+		defer onDependencyResolvedMutex.Unlock()
+		onDependencyResolved(name, ver, found)
+	}
 	return func(dependency *entities.Dependency) (bool, error) {
 		splitDepId := strings.SplitN(dependency.Id, ":", 2)
 		name := splitDepId[0]
 		ver := splitDepId[1]
 
 		// Get dependency info.
-		checksum, fileType, err := getDependencyInfo(name, ver, previousBuildDependencies, servicesManager)
+		lookupStart := time.Now()
+		// Network errors and server errors are retried by the services manager's HTTP client, according to its retry configuration.
+		checksum, fileType, err := getDependencyInfo(name, ver, &lookupOptions)
+		lookupOptions.lookupDurations.record(dependency.Id, time.Since(lookupStart))
+		if err == nil && !checksum.IsEmpty() && !hasChecksumOfType(checksum, requiredChecksumType) {
+			log.Debug(dependency.Id, "has no", string(requiredChecksumType), "checksum, and is therefore considered missing.")
+			checksum = entities.Checksum{}
+		}
 		if err != nil || checksum.IsEmpty() {
+			notifyResolved(name, ver, false)
 			missingDepsChan <- dependency.Id
 			return false, err
 		}
+		notifyResolved(name, ver, true)
 
 		// Update dependency.
 		dependency.Type = fileType
@@ -1,9 +1,25 @@
 package yarn
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/jfrog/build-info-go/entities"
+	commandUtils "github.com/jfrog/jfrog-cli-core/v2/artifactory/commands/utils"
+	"github.com/jfrog/jfrog-cli-core/v2/common/project"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	"github.com/jfrog/jfrog-client-go/artifactory"
+	"github.com/jfrog/jfrog-client-go/artifactory/services"
+	servicesUtils "github.com/jfrog/jfrog-client-go/artifactory/services/utils"
 	"github.com/jfrog/jfrog-client-go/utils/tests"
 	"github.com/stretchr/testify/assert"
 )
@@ -73,3 +89,665 @@ func TestExtractAuthValuesFromNpmAuth(t *testing.T) {
 		assert.Equal(t, testCase.expectedExtractedAuthToken, actualExtractedAuthToken)
 	}
 }
+
+// Records the AQL queries, and responds to them by the package names they match.
+type aqlMockServicesManager struct {
+	artifactory.EmptyArtifactoryServicesManager
+	// AQL responses by the package names they match.
+	aqlResponses map[string]string
+	// The response to the queries which don't match any of the package names. If empty, no results are returned.
+	defaultAqlResponse string
+	// Delays of the AQL queries by the package names they match.
+	delays map[string]time.Duration
+	mutex  sync.Mutex
+	// The received queries, in order.
+	aqlQueries []string
+	// If set, the AQL queries fail with this error.
+	aqlErr error
+	// The repositories of the projects, by the projects' keys.
+	projectRepositories map[string][]services.RepositoryDetails
+}
+
+func (amsm *aqlMockServicesManager) GetAllRepositoriesFiltered(params services.RepositoriesFilterParams) (*[]services.RepositoryDetails, error) {
+	repositories := amsm.projectRepositories[params.ProjectKey]
+	return &repositories, nil
+}
+
+func (amsm *aqlMockServicesManager) Aql(query string) (io.ReadCloser, error) {
+	amsm.mutex.Lock()
+	amsm.aqlQueries = append(amsm.aqlQueries, query)
+	amsm.mutex.Unlock()
+	if amsm.aqlErr != nil {
+		return nil, amsm.aqlErr
+	}
+	for name, delay := range amsm.delays {
+		if strings.Contains(query, `"@npm.name":"`+name+`"`) {
+			time.Sleep(delay)
+		}
+	}
+	for name, response := range amsm.aqlResponses {
+		if strings.Contains(query, `"@npm.name":"`+name+`"`) {
+			return io.NopCloser(strings.NewReader(response)), nil
+		}
+	}
+	response := amsm.defaultAqlResponse
+	if response == "" {
+		response = `{"results":[]}`
+	}
+	return io.NopCloser(strings.NewReader(response)), nil
+}
+
+func TestCollectChecksumsOnDependencyResolved(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"send": `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1","actual_md5":"send-md5"}]}`,
+	}}
+	previousBuildDependencies := map[string]*entities.Dependency{
+		"ms:2.0.0": {Id: "ms:2.0.0", Type: "tgz", Checksum: entities.Checksum{Sha1: "ms-sha1", Md5: "ms-md5"}},
+	}
+	resolved := make(map[string]bool)
+	onDependencyResolved := func(name, version string, found bool) {
+		// The calls are synchronized, so no lock is needed here.
+		resolved[name+":"+version] = found
+	}
+	missingDepsChan := make(chan string, 1)
+	collectChecksumsFunc := createCollectChecksumsFunc(&checksumLookupOptions{previousBuildDependencies: previousBuildDependencies, servicesManager: servicesManager, onDependencyResolved: onDependencyResolved}, missingDepsChan)
+
+	var wg sync.WaitGroup
+	for _, depId := range []string{"send:0.16.2", "ms:2.0.0", "debug:4.1.1"} {
+		wg.Add(1)
+		go func(depId string) {
+			defer wg.Done()
+			_, err := collectChecksumsFunc(&entities.Dependency{Id: depId})
+			assert.NoError(t, err)
+		}(depId)
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]bool{"send:0.16.2": true, "ms:2.0.0": true, "debug:4.1.1": false}, resolved)
+	assert.Equal(t, "debug:4.1.1", <-missingDepsChan)
+}
+
+func TestCollectChecksumsLookupDurations(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{
+		aqlResponses: map[string]string{
+			"send": `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1","actual_md5":"send-md5"}]}`,
+		},
+		delays: map[string]time.Duration{"send": 50 * time.Millisecond},
+	}
+	yc := NewYarnCommand()
+	assert.Nil(t, yc.GetDependencyLookupDurations())
+	yc.lookupDurations = &dependencyLookupDurations{}
+	missingDepsChan := make(chan string, 1)
+	collectChecksumsFunc := createCollectChecksumsFunc(&checksumLookupOptions{servicesManager: servicesManager, lookupDurations: yc.lookupDurations}, missingDepsChan)
+
+	var wg sync.WaitGroup
+	for _, depId := range []string{"send:0.16.2", "debug:4.1.1"} {
+		wg.Add(1)
+		go func(depId string) {
+			defer wg.Done()
+			_, err := collectChecksumsFunc(&entities.Dependency{Id: depId})
+			assert.NoError(t, err)
+		}(depId)
+	}
+	wg.Wait()
+
+	// The durations of both the found and the missing dependencies are recorded.
+	durations := yc.GetDependencyLookupDurations()
+	assert.Len(t, durations, 2)
+	assert.GreaterOrEqual(t, durations["send:0.16.2"], 50*time.Millisecond)
+	assert.Contains(t, durations, "debug:4.1.1")
+	assert.Less(t, durations["debug:4.1.1"], durations["send:0.16.2"])
+	// The returned durations are a copy.
+	durations["send:0.16.2"] = 0
+	assert.NotZero(t, yc.GetDependencyLookupDurations()["send:0.16.2"])
+}
+
+func TestCollectChecksumsWithRequiredChecksumType(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"send":  `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1","actual_md5":"send-md5"}]}`,
+		"debug": `{"results":[{"name":"debug-4.1.1.tgz","actual_sha1":"debug-sha1","actual_md5":"debug-md5","sha256":"debug-sha256"}]}`,
+	}}
+	testCases := []struct {
+		name                 string
+		requiredChecksumType ChecksumType
+		expectedMissing      []string
+	}{
+		{name: "any", expectedMissing: nil},
+		{name: "sha1", requiredChecksumType: Sha1ChecksumType, expectedMissing: nil},
+		{name: "sha256", requiredChecksumType: Sha256ChecksumType, expectedMissing: []string{"send:0.16.2"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			missingDepsChan := make(chan string, 2)
+			collectChecksumsFunc := createCollectChecksumsFunc(&checksumLookupOptions{servicesManager: servicesManager, requiredChecksumType: testCase.requiredChecksumType}, missingDepsChan)
+			for _, depId := range []string{"send:0.16.2", "debug:4.1.1"} {
+				dependency := &entities.Dependency{Id: depId}
+				found, err := collectChecksumsFunc(dependency)
+				assert.NoError(t, err)
+				if found {
+					assert.NotEmpty(t, dependency.Sha1)
+				}
+			}
+			close(missingDepsChan)
+			var missing []string
+			for depId := range missingDepsChan {
+				missing = append(missing, depId)
+			}
+			assert.Equal(t, testCase.expectedMissing, missing)
+		})
+	}
+}
+
+func TestCollectChecksumsFromPreviousBuildWithRequiredChecksumType(t *testing.T) {
+	previousBuild := &entities.PublishedBuildInfo{BuildInfo: entities.BuildInfo{Modules: []entities.Module{{Dependencies: []entities.Dependency{
+		{Id: "send:0.16.2", Type: "tgz", Checksum: entities.Checksum{Sha1: "send-sha1", Sha256: "send-sha256"}},
+		{Id: "debug:4.1.1", Type: "tgz", Checksum: entities.Checksum{Sha1: "debug-previous-sha1"}},
+	}}}}}
+	previousBuildDependencies, err := getDependenciesFromPreviousBuild(&buildInfoMockServicesManager{builds: map[string]*entities.PublishedBuildInfo{
+		servicesUtils.LatestBuildNumberKey: previousBuild,
+	}}, "yarn-build", "")
+	assert.NoError(t, err)
+	// debug has no SHA-256 checksum in the previous build, so it's looked up in Artifactory.
+	servicesManager := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"debug": `{"results":[{"name":"debug-4.1.1.tgz","actual_sha1":"debug-sha1","sha256":"debug-sha256"}]}`,
+	}}
+	missingDepsChan := make(chan string, 2)
+	collectChecksumsFunc := createCollectChecksumsFunc(&checksumLookupOptions{previousBuildDependencies: previousBuildDependencies, servicesManager: servicesManager, requiredChecksumType: Sha256ChecksumType}, missingDepsChan)
+
+	send := &entities.Dependency{Id: "send:0.16.2"}
+	found, err := collectChecksumsFunc(send)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, entities.Checksum{Sha1: "send-sha1", Sha256: "send-sha256"}, send.Checksum)
+
+	debug := &entities.Dependency{Id: "debug:4.1.1"}
+	found, err = collectChecksumsFunc(debug)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "debug-sha1", debug.Sha1)
+	assert.Equal(t, "debug-sha256", debug.Sha256)
+	close(missingDepsChan)
+	assert.Empty(t, missingDepsChan)
+}
+
+func TestGetDependencyInfoLocalChecksumFallback(t *testing.T) {
+	cacheFolder := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(cacheFolder, "debug-npm-4.1.1-0d7bb8d3b1-e5e5bd8a8d.zip"), []byte("debug archive"), 0644))
+	locateCachedPackage := newYarnCacheLocator(cacheFolder)
+	servicesManager := &aqlMockServicesManager{}
+
+	// Without the fallback, a dependency which isn't found in Artifactory has no checksum.
+	checksum, _, err := getDependencyInfo("debug", "4.1.1", &checksumLookupOptions{servicesManager: servicesManager})
+	assert.NoError(t, err)
+	assert.True(t, checksum.IsEmpty())
+
+	// With the fallback, the checksum is calculated from the cached archive.
+	checksum, fileType, err := getDependencyInfo("debug", "4.1.1", &checksumLookupOptions{servicesManager: servicesManager, locateCachedPackage: locateCachedPackage})
+	assert.NoError(t, err)
+	assert.Equal(t, "zip", fileType)
+	assert.NotEmpty(t, checksum.Sha1)
+	assert.NotEmpty(t, checksum.Md5)
+	assert.NotEmpty(t, checksum.Sha256)
+
+	// A dependency missing in both Artifactory and the cache has no checksum.
+	checksum, _, err = getDependencyInfo("ms", "2.0.0", &checksumLookupOptions{servicesManager: servicesManager, locateCachedPackage: locateCachedPackage})
+	assert.NoError(t, err)
+	assert.True(t, checksum.IsEmpty())
+}
+
+func TestGetDependencyInfoWithProjectKey(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{
+		aqlResponses: map[string]string{
+			"send": `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1"}]}`,
+		},
+		projectRepositories: map[string][]services.RepositoryDetails{"proj": {
+			{Key: "proj-npm-local", Type: "LOCAL"},
+			// Shared with the project, so its key doesn't start with the project key.
+			{Key: "npm-remote", Type: "REMOTE"},
+		}},
+	}
+	searchCriteria, err := NewYarnCommand().SetProjectKey("proj").createArtifactSearchCriteria(servicesManager)
+	assert.NoError(t, err)
+	checksum, _, err := getDependencyInfo("send", "0.16.2", &checksumLookupOptions{servicesManager: servicesManager, searchCriteria: searchCriteria})
+	assert.NoError(t, err)
+	assert.Equal(t, "send-sha1", checksum.Sha1)
+	if assert.Len(t, servicesManager.aqlQueries, 1) {
+		assert.Contains(t, servicesManager.aqlQueries[0], `{"$or":[{"repo":"proj-npm-local"},{"repo":"npm-remote-cache"}]}`)
+	}
+
+	// A project without npm repositories fails the collection, rather than searching all the repositories.
+	_, err = NewYarnCommand().SetProjectKey("other").createArtifactSearchCriteria(servicesManager)
+	assert.ErrorContains(t, err, "no npm repositories were found in the JFrog Project 'other'")
+}
+
+func TestGetDependencyInfoFromReleaseBundle(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"send": `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"bundle-sha1"}]}`,
+	}}
+	yc := NewYarnCommand().SetReleaseBundle("my-bundle", "1.0.0")
+	checksum, fileType, err := getDependencyInfo("send", "0.16.2", &checksumLookupOptions{servicesManager: servicesManager, searchCriteria: commandUtils.ArtifactSearchCriteria{ReleaseBundle: yc.releaseBundle}})
+	assert.NoError(t, err)
+	assert.Equal(t, "tgz", fileType)
+	assert.Equal(t, "bundle-sha1", checksum.Sha1)
+
+	// Without a release bundle, the normal lookup is used.
+	_, _, err = getDependencyInfo("send", "0.16.2", &checksumLookupOptions{servicesManager: servicesManager})
+	assert.NoError(t, err)
+	if assert.Len(t, servicesManager.aqlQueries, 2) {
+		assert.Contains(t, servicesManager.aqlQueries[0], `"release_artifact.release.name":"my-bundle","release_artifact.release.version":"1.0.0"`)
+		assert.NotContains(t, servicesManager.aqlQueries[1], "release_artifact")
+	}
+}
+
+func TestGetDependencyInfoFromSecondaryServer(t *testing.T) {
+	primary := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"debug": `{"results":[{"name":"debug-4.1.1.tgz","actual_sha1":"primary-debug-sha1"}]}`,
+	}}
+	secondary := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"send": `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"secondary-send-sha1"}]}`,
+	}}
+	// Finds any dependency.
+	lastSecondary := &aqlMockServicesManager{defaultAqlResponse: `{"results":[{"name":"ms-2.1.2.tgz","actual_sha1":"ms-sha1"}]}`}
+	secondaryServicesManagers := []artifactory.ArtifactoryServicesManager{&aqlMockServicesManager{}, secondary, lastSecondary}
+
+	// A dependency found in the primary server isn't looked up in the secondary servers.
+	checksum, _, err := getDependencyInfo("debug", "4.1.1", &checksumLookupOptions{servicesManager: primary, secondaryServicesManagers: secondaryServicesManagers})
+	assert.NoError(t, err)
+	assert.Equal(t, "primary-debug-sha1", checksum.Sha1)
+	assert.Empty(t, lastSecondary.aqlQueries)
+
+	// The secondary servers are queried in order, until the dependency is found.
+	checksum, fileType, err := getDependencyInfo("send", "0.16.2", &checksumLookupOptions{servicesManager: primary, secondaryServicesManagers: secondaryServicesManagers})
+	assert.NoError(t, err)
+	assert.Equal(t, "tgz", fileType)
+	assert.Equal(t, "secondary-send-sha1", checksum.Sha1)
+	assert.Empty(t, lastSecondary.aqlQueries)
+
+	checksum, _, err = getDependencyInfo("ms", "2.1.2", &checksumLookupOptions{servicesManager: primary, secondaryServicesManagers: secondaryServicesManagers})
+	assert.NoError(t, err)
+	assert.Equal(t, "ms-sha1", checksum.Sha1)
+	assert.Len(t, lastSecondary.aqlQueries, 1)
+
+	// A failing secondary server is skipped.
+	failingSecondary := &aqlMockServicesManager{aqlErr: errors.New("connection refused")}
+	checksum, _, err = getDependencyInfo("send", "0.16.2", &checksumLookupOptions{servicesManager: primary,
+		secondaryServicesManagers: []artifactory.ArtifactoryServicesManager{failingSecondary, secondary}})
+	assert.NoError(t, err)
+	assert.Equal(t, "secondary-send-sha1", checksum.Sha1)
+	assert.Len(t, failingSecondary.aqlQueries, 1)
+
+	// The dependency is looked up in the local cache, if it isn't found in the secondary servers because they fail.
+	cacheFolder := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(cacheFolder, "ms-npm-2.1.2-2b6e3e1d6c-6d9b2a8b7c.zip"), []byte("ms archive"), 0644))
+	checksum, _, err = getDependencyInfo("ms", "2.1.2", &checksumLookupOptions{servicesManager: primary,
+		secondaryServicesManagers: []artifactory.ArtifactoryServicesManager{failingSecondary}, locateCachedPackage: newYarnCacheLocator(cacheFolder)})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, checksum.Sha1)
+
+	// Without secondary servers, a dependency which isn't found in the primary server has no checksum.
+	checksum, _, err = getDependencyInfo("ms", "2.1.2", &checksumLookupOptions{servicesManager: primary})
+	assert.NoError(t, err)
+	assert.True(t, checksum.IsEmpty())
+}
+
+func TestCollectArtifactProperties(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"send": `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1","properties":[` +
+			`{"key":"build.name","value":"send-build"},{"key":"build.number","value":"7"},{"key":"npm.name","value":"send"}]}]}`,
+	}}
+	yc := NewYarnCommand().SetCollectArtifactProperties([]string{"build.name", "build.number"})
+	yc.artifactProperties = &dependencyArtifactProperties{keys: yc.artifactPropertyKeys}
+	missingDepsChan := make(chan string, 1)
+	collectChecksumsFunc := createCollectChecksumsFunc(&checksumLookupOptions{servicesManager: servicesManager, artifactProperties: yc.artifactProperties}, missingDepsChan)
+
+	found, err := collectChecksumsFunc(&entities.Dependency{Id: "send:0.16.2"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	found, err = collectChecksumsFunc(&entities.Dependency{Id: "ms:2.0.0"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Equal(t, map[string]map[string]string{"send:0.16.2": {"build.name": "send-build", "build.number": "7"}}, yc.GetDependencyArtifactProperties())
+	if assert.Len(t, servicesManager.aqlQueries, 2) {
+		assert.Contains(t, servicesManager.aqlQueries[0], `"property.*"`)
+	}
+
+	// Without properties to collect, no properties are collected.
+	yc = NewYarnCommand()
+	yc.artifactProperties = &dependencyArtifactProperties{keys: yc.artifactPropertyKeys}
+	collectChecksumsFunc = createCollectChecksumsFunc(&checksumLookupOptions{servicesManager: servicesManager, artifactProperties: yc.artifactProperties}, missingDepsChan)
+	found, err = collectChecksumsFunc(&entities.Dependency{Id: "send:0.16.2"})
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Empty(t, yc.GetDependencyArtifactProperties())
+	if assert.Len(t, servicesManager.aqlQueries, 3) {
+		assert.NotContains(t, servicesManager.aqlQueries[2], "property")
+	}
+}
+
+func TestCollectArtifactPropertiesOfPreviousBuildDependencies(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"send": `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1","properties":[{"key":"build.name","value":"send-build"}]}]}`,
+	}}
+	previousBuildDependencies := map[string]*entities.Dependency{
+		"send:0.16.2": {Id: "send:0.16.2", Type: "tgz", Checksum: entities.Checksum{Sha1: "previous-sha1"}},
+	}
+	yc := NewYarnCommand().SetCollectArtifactProperties([]string{"build.name"})
+	yc.artifactProperties = &dependencyArtifactProperties{keys: yc.artifactPropertyKeys}
+	options := &checksumLookupOptions{previousBuildDependencies: previousBuildDependencies, servicesManager: servicesManager, artifactProperties: yc.artifactProperties}
+
+	// The checksum is taken from the previous build, and the properties from Artifactory.
+	checksum, _, err := getDependencyInfo("send", "0.16.2", options)
+	assert.NoError(t, err)
+	assert.Equal(t, "previous-sha1", checksum.Sha1)
+	assert.Equal(t, map[string]map[string]string{"send:0.16.2": {"build.name": "send-build"}}, yc.GetDependencyArtifactProperties())
+
+	// Without properties to collect, Artifactory isn't queried.
+	options.artifactProperties = nil
+	_, _, err = getDependencyInfo("send", "0.16.2", options)
+	assert.NoError(t, err)
+	assert.Len(t, servicesManager.aqlQueries, 1)
+}
+
+func TestForceDependencyType(t *testing.T) {
+	servicesManager := &aqlMockServicesManager{aqlResponses: map[string]string{
+		"send": `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1"}]}`,
+	}}
+	missingDepsChan := make(chan string, 1)
+	collectChecksumsFunc := createCollectChecksumsFunc(&checksumLookupOptions{servicesManager: servicesManager}, missingDepsChan)
+
+	// By default, the type is the artifact's extension.
+	dependency := &entities.Dependency{Id: "send:0.16.2"}
+	found, err := collectChecksumsFunc(dependency)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "tgz", dependency.Type)
+
+	dependency = &entities.Dependency{Id: "send:0.16.2"}
+	found, err = forceDependencyType(collectChecksumsFunc, "npm")(dependency)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "npm", dependency.Type)
+	assert.Equal(t, "send-sha1", dependency.Sha1)
+
+	// Missing dependencies aren't in the build-info, so their type isn't set.
+	dependency = &entities.Dependency{Id: "ms:2.0.0"}
+	found, err = forceDependencyType(collectChecksumsFunc, "npm")(dependency)
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Empty(t, dependency.Type)
+}
+
+func TestCreateSecondaryLookupServicesManagers(t *testing.T) {
+	yc := NewYarnCommand().AddSecondaryChecksumServer(&config.ServerDetails{ArtifactoryUrl: "https://eu.jfrog.io/artifactory/"}).
+		AddSecondaryChecksumServer(&config.ServerDetails{ArtifactoryUrl: "https://us.jfrog.io/artifactory/"})
+	servicesManagers, err := yc.createSecondaryLookupServicesManagers()
+	assert.NoError(t, err)
+	if assert.Len(t, servicesManagers, 2) {
+		assert.Equal(t, "https://eu.jfrog.io/artifactory/", servicesManagers[0].GetConfig().GetServiceDetails().GetUrl())
+		assert.Equal(t, "https://us.jfrog.io/artifactory/", servicesManagers[1].GetConfig().GetServiceDetails().GetUrl())
+	}
+}
+
+type buildInfoMockServicesManager struct {
+	artifactory.EmptyArtifactoryServicesManager
+	// Published builds by their numbers.
+	builds map[string]*entities.PublishedBuildInfo
+}
+
+func (bimsm *buildInfoMockServicesManager) GetBuildInfo(params services.BuildInfoParams) (*entities.PublishedBuildInfo, bool, error) {
+	publishedBuild, found := bimsm.builds[params.BuildNumber]
+	return publishedBuild, found, nil
+}
+
+func createPublishedBuild(dependencyId, sha1 string) *entities.PublishedBuildInfo {
+	return &entities.PublishedBuildInfo{BuildInfo: entities.BuildInfo{Modules: []entities.Module{
+		{Dependencies: []entities.Dependency{{Id: dependencyId, Type: "tgz", Checksum: entities.Checksum{Sha1: sha1}}}},
+	}}}
+}
+
+func TestCreateLookupServicesManagerRetries(t *testing.T) {
+	testCases := []struct {
+		name             string
+		retryConfig      *coreutils.RetryConfig
+		expectedRetries  int
+		expectedWaitTime int
+	}{
+		{name: "default", expectedRetries: 3},
+		{name: "custom", retryConfig: &coreutils.RetryConfig{MaxRetries: 7, WaitMs: 500}, expectedRetries: 7, expectedWaitTime: 500},
+		{name: "no retries", retryConfig: &coreutils.RetryConfig{MaxRetries: 0}, expectedRetries: 0},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			yarnCmd := NewYarnCommand().SetDependencyLookupRetryConfig(testCase.retryConfig)
+			yarnCmd.serverDetails = &config.ServerDetails{ArtifactoryUrl: "https://acme.jfrog.io/artifactory/"}
+			servicesManager, err := yarnCmd.createLookupServicesManager(yarnCmd.serverDetails)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedRetries, servicesManager.GetConfig().GetHttpRetries())
+			assert.Equal(t, testCase.expectedWaitTime, servicesManager.GetConfig().GetHttpRetryWaitMilliSecs())
+		})
+	}
+}
+
+// A mock HTTP transport, which records the requests, and responds to them with the given body.
+type mockTransport struct {
+	requests []*http.Request
+	body     string
+}
+
+func (mt *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	mt.requests = append(mt.requests, req)
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{}, Body: io.NopCloser(strings.NewReader(mt.body)), Request: req}, nil
+}
+
+func TestCreateLookupServicesManagerWithTransport(t *testing.T) {
+	transport := &mockTransport{body: `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1"}]}`}
+	yarnCmd := NewYarnCommand().SetDependencyLookupTransport(transport).SetUserAgent("my-agent/1.0.0")
+	yarnCmd.serverDetails = &config.ServerDetails{ArtifactoryUrl: "https://acme.jfrog.io/artifactory/", AccessToken: "my-token"}
+	servicesManager, err := yarnCmd.createLookupServicesManager(yarnCmd.serverDetails)
+	assert.NoError(t, err)
+	checksum, fileType, err := getDependencyInfo("send", "0.16.2", &checksumLookupOptions{servicesManager: servicesManager})
+	assert.NoError(t, err)
+	assert.Equal(t, "send-sha1", checksum.Sha1)
+	assert.Equal(t, "tgz", fileType)
+	if assert.Len(t, transport.requests, 1) {
+		request := transport.requests[0]
+		assert.Equal(t, http.MethodPost, request.Method)
+		assert.Equal(t, "https://acme.jfrog.io/artifactory/api/search/aql", request.URL.String())
+		assert.Equal(t, "Bearer my-token", request.Header.Get("Authorization"))
+		assert.Equal(t, "my-agent/1.0.0", request.Header.Get("User-Agent"))
+	}
+}
+
+func TestGetDependenciesFromPreviousBuild(t *testing.T) {
+	servicesManager := &buildInfoMockServicesManager{builds: map[string]*entities.PublishedBuildInfo{
+		servicesUtils.LatestBuildNumberKey: createPublishedBuild("send:0.16.2", "latest-sha1"),
+		"5":                                createPublishedBuild("send:0.16.2", "build-5-sha1"),
+	}}
+	testCases := []struct {
+		name         string
+		buildNumber  string
+		expectedSha1 string
+	}{
+		{"latest build", "", "latest-sha1"},
+		{"pinned build", "5", "build-5-sha1"},
+		{"missing build", "6", ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dependencies, err := getDependenciesFromPreviousBuild(servicesManager, "yarn-build", testCase.buildNumber)
+			assert.NoError(t, err)
+			if testCase.expectedSha1 == "" {
+				assert.Empty(t, dependencies)
+				return
+			}
+			if assert.Contains(t, dependencies, "send:0.16.2") {
+				assert.Equal(t, testCase.expectedSha1, dependencies["send:0.16.2"].Sha1)
+			}
+		})
+	}
+}
+
+func TestReportMissingDependencies(t *testing.T) {
+	missingDependencies := []string{"yarn-dep2:2.0.0", "@scope/yarn-dep1:1.0.0"}
+	testCases := []struct {
+		name           string
+		format         MissingDependenciesFormat
+		expectedOutput string
+	}{
+		{"text", MissingDependenciesText, "@scope/yarn-dep1:1.0.0\nyarn-dep2:2.0.0\n"},
+		{"json", MissingDependenciesJson, `[{"name":"@scope/yarn-dep1","version":"1.0.0"},{"name":"yarn-dep2","version":"2.0.0"}]` + "\n"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var sink strings.Builder
+			yarnCmd := NewYarnCommand().SetMissingDependenciesSink(&sink, testCase.format)
+			assert.NoError(t, yarnCmd.reportMissingDependencies(append([]string{}, missingDependencies...)))
+			assert.Equal(t, testCase.expectedOutput, sink.String())
+		})
+	}
+
+	t.Run("json without missing dependencies", func(t *testing.T) {
+		var sink strings.Builder
+		yarnCmd := NewYarnCommand().SetMissingDependenciesSink(&sink, MissingDependenciesJson)
+		assert.NoError(t, yarnCmd.reportMissingDependencies(nil))
+		assert.Equal(t, "[]\n", sink.String())
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		yarnCmd := NewYarnCommand().SetMissingDependenciesSink(io.Discard, "xml")
+		assert.ErrorContains(t, yarnCmd.reportMissingDependencies(missingDependencies), "unsupported missing dependencies format")
+	})
+}
+
+// A mock HTTP transport, which responds to the requests with the given statuses in order, and then with the last one.
+// Successful responses return the given body.
+type statusSequenceTransport struct {
+	statuses []int
+	body     string
+	requests int
+}
+
+func (sst *statusSequenceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := sst.statuses[min(sst.requests, len(sst.statuses)-1)]
+	sst.requests++
+	body := sst.body
+	if status != http.StatusOK {
+		body = http.StatusText(status)
+	}
+	return &http.Response{StatusCode: status, Status: fmt.Sprintf("%d %s", status, http.StatusText(status)), Header: http.Header{},
+		Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func TestCollectChecksumsLookupErrors(t *testing.T) {
+	const foundBody = `{"results":[{"name":"send-0.16.2.tgz","actual_sha1":"send-sha1"}]}`
+	testCases := []struct {
+		name             string
+		statuses         []int
+		body             string
+		expectedFound    bool
+		expectedErr      bool
+		expectedRequests int
+	}{
+		{name: "found", statuses: []int{http.StatusOK}, body: foundBody, expectedFound: true, expectedRequests: 1},
+		{name: "server errors then found", statuses: []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK}, body: foundBody, expectedFound: true, expectedRequests: 3},
+		{name: "server errors exhaust the retries", statuses: []int{http.StatusBadGateway}, expectedErr: true, expectedRequests: 3},
+		{name: "not found", statuses: []int{http.StatusOK}, body: `{"results":[]}`, expectedRequests: 1},
+		{name: "terminal error", statuses: []int{http.StatusUnauthorized}, expectedErr: true, expectedRequests: 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transport := &statusSequenceTransport{statuses: testCase.statuses, body: testCase.body}
+			yarnCmd := NewYarnCommand().SetDependencyLookupTransport(transport).SetDependencyLookupRetryConfig(&coreutils.RetryConfig{MaxRetries: 2, WaitMs: 1})
+			yarnCmd.serverDetails = &config.ServerDetails{ArtifactoryUrl: "https://acme.jfrog.io/artifactory/"}
+			servicesManager, err := yarnCmd.createLookupServicesManager(yarnCmd.serverDetails)
+			assert.NoError(t, err)
+			missingDepsChan := make(chan string, 1)
+			collectChecksumsFunc := createCollectChecksumsFunc(&checksumLookupOptions{servicesManager: servicesManager}, missingDepsChan)
+
+			dependency := &entities.Dependency{Id: "send:0.16.2"}
+			found, err := collectChecksumsFunc(dependency)
+			assert.Equal(t, testCase.expectedErr, err != nil, err)
+			assert.Equal(t, testCase.expectedFound, found)
+			assert.Equal(t, testCase.expectedRequests, transport.requests)
+			if testCase.expectedFound {
+				assert.Equal(t, "send-sha1", dependency.Sha1)
+				assert.Empty(t, missingDepsChan)
+			} else {
+				assert.Equal(t, "send:0.16.2", <-missingDepsChan)
+			}
+		})
+	}
+}
+
+func TestReadConfigFileWithOverrides(t *testing.T) {
+	t.Setenv(coreutils.HomeDir, t.TempDir())
+	assert.NoError(t, config.SaveServersConf([]*config.ServerDetails{
+		{ServerId: "config-server", ArtifactoryUrl: "https://config.jfrog.io/artifactory/"},
+		{ServerId: "override-server", ArtifactoryUrl: "https://override.jfrog.io/artifactory/"},
+	}))
+	configFilePath := filepath.Join(t.TempDir(), "yarn.yaml")
+	assert.NoError(t, os.WriteFile(configFilePath, []byte("version: 1\ntype: yarn\nresolver:\n  repo: config-repo\n  serverId: config-server\n"), 0644))
+
+	testCases := []struct {
+		name             string
+		configFilePath   string
+		repoOverride     string
+		serverOverride   string
+		expectedRepo     string
+		expectedServerId string
+	}{
+		{name: "config file", configFilePath: configFilePath, expectedRepo: "config-repo", expectedServerId: "config-server"},
+		{name: "repo override", configFilePath: configFilePath, repoOverride: "override-repo", expectedRepo: "override-repo", expectedServerId: "config-server"},
+		{name: "server override", configFilePath: configFilePath, serverOverride: "override-server", expectedRepo: "config-repo", expectedServerId: "override-server"},
+		// The config file isn't read, so it may be missing.
+		{name: "both overrides", configFilePath: filepath.Join(t.TempDir(), "missing.yaml"), repoOverride: "override-repo", serverOverride: "override-server",
+			expectedRepo: "override-repo", expectedServerId: "override-server"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			yarnCmd := NewYarnCommand().SetConfigFilePath(testCase.configFilePath).
+				SetRepoOverride(testCase.repoOverride).SetServerOverride(testCase.serverOverride)
+			assert.NoError(t, yarnCmd.readConfigFile())
+			assert.Equal(t, testCase.expectedRepo, yarnCmd.repo)
+			if assert.NotNil(t, yarnCmd.serverDetails) {
+				assert.Equal(t, testCase.expectedServerId, yarnCmd.serverDetails.ServerId)
+			}
+		})
+	}
+
+	// An overriding server which isn't configured.
+	yarnCmd := NewYarnCommand().SetConfigFilePath(configFilePath).SetRepoOverride("override-repo").SetServerOverride("missing-server")
+	assert.ErrorContains(t, yarnCmd.readConfigFile(), "Server ID 'missing-server' does not exist.")
+}
+
+func TestReadConfigFileFormats(t *testing.T) {
+	t.Setenv(coreutils.HomeDir, t.TempDir())
+	assert.NoError(t, config.SaveServersConf([]*config.ServerDetails{{ServerId: "config-server", ArtifactoryUrl: "https://config.jfrog.io/artifactory/"}}))
+	configDir := t.TempDir()
+	yamlConfig := []byte("version: 1\ntype: yarn\nresolver:\n  repo: config-repo\n  serverId: config-server\n")
+	jsonConfig := []byte(`{"version": 1, "type": "yarn", "resolver": {"repo": "config-repo", "serverId": "config-server"}}`)
+
+	testCases := []struct {
+		name         string
+		fileName     string
+		content      []byte
+		configFormat project.ConfigType
+	}{
+		{name: "yaml", fileName: "yarn.yaml", content: yamlConfig},
+		{name: "json", fileName: "yarn.json", content: jsonConfig},
+		{name: "json detected by content", fileName: "yarn-json.conf", content: jsonConfig},
+		{name: "explicit json", fileName: "yarn-json.yaml", content: jsonConfig, configFormat: project.JSON},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			configFilePath := filepath.Join(configDir, testCase.fileName)
+			assert.NoError(t, os.WriteFile(configFilePath, testCase.content, 0644))
+			yarnCmd := NewYarnCommand().SetConfigFilePath(configFilePath).SetConfigFormat(testCase.configFormat)
+			assert.NoError(t, yarnCmd.readConfigFile())
+			assert.Equal(t, "config-repo", yarnCmd.repo)
+			if assert.NotNil(t, yarnCmd.serverDetails) {
+				assert.Equal(t, "config-server", yarnCmd.serverDetails.ServerId)
+			}
+		})
+	}
+}
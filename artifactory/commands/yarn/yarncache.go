@@ -0,0 +1,49 @@
+package yarn
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/jfrog/jfrog-cli-core/v2/artifactory/utils/yarn"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"github.com/jfrog/jfrog-client-go/utils/log"
+)
+
+const yarnCacheFolderConfigName = "cacheFolder"
+
+// Locates the archive of a package in the local Yarn cache, and returns its path.
+type cachedPackageLocator func(name, version string) (string, error)
+
+// Returns a locator of packages in the local Yarn cache, or nil if the Yarn cache can't be found.
+func (yc *YarnCommand) createYarnCacheLocator() cachedPackageLocator {
+	cacheFolder, err := yarn.ConfigGet(yarnCacheFolderConfigName, yc.executablePath, false)
+	if err == nil {
+		return newYarnCacheLocator(cacheFolder)
+	}
+	log.Warn("The checksums of dependencies which are not found in Artifactory can't be calculated from the local Yarn cache:", err.Error())
+	return nil
+}
+
+// Returns a locator of packages in the given Yarn cache folder (as returned by 'yarn config get cacheFolder').
+// Yarn keeps the npm packages in its cache as zip archives, named <scope>-<name>-npm-<version>-<hash>-<checksum>.zip,
+// where the scope and its hyphen are omitted for packages without a scope.
+func newYarnCacheLocator(cacheFolder string) cachedPackageLocator {
+	return func(name, version string) (string, error) {
+		archiveRegexp, err := regexp.Compile("^" + regexp.QuoteMeta(strings.Replace(name, "/", "-", 1)+"-npm-"+version+"-") + `[0-9a-f]+(-[0-9a-z]+)?\.zip$`)
+		if err != nil {
+			return "", errorutils.CheckError(err)
+		}
+		entries, err := os.ReadDir(cacheFolder)
+		if err != nil {
+			return "", errorutils.CheckError(err)
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && archiveRegexp.MatchString(entry.Name()) {
+				return filepath.Join(cacheFolder, entry.Name()), nil
+			}
+		}
+		return "", errorutils.CheckErrorf("%s:%s is not in the Yarn cache %s", name, version, cacheFolder)
+	}
+}
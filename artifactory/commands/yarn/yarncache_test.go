@@ -0,0 +1,45 @@
+package yarn
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestYarnCacheLocator(t *testing.T) {
+	cacheFolder := t.TempDir()
+	for _, fileName := range []string{
+		"send-npm-0.16.2-2871bc2ab3-8e2d0ab5c5.zip",
+		"send-npm-0.16.2-beta.1-3c1e2a4b5d-9f8e7d6c5b.zip",
+		"@jfrog-package-npm-1.0.0-5a4b3c2d1e-1a2b3c4d5e.zip",
+		"debug-npm-4.1.1-0d7bb8d3b1.zip",
+	} {
+		assert.NoError(t, os.WriteFile(filepath.Join(cacheFolder, fileName), []byte(fileName), 0644))
+	}
+	locateCachedPackage := newYarnCacheLocator(cacheFolder)
+	testCases := []struct {
+		name             string
+		version          string
+		expectedFileName string
+	}{
+		{"send", "0.16.2", "send-npm-0.16.2-2871bc2ab3-8e2d0ab5c5.zip"},
+		{"send", "0.16.2-beta.1", "send-npm-0.16.2-beta.1-3c1e2a4b5d-9f8e7d6c5b.zip"},
+		{"@jfrog/package", "1.0.0", "@jfrog-package-npm-1.0.0-5a4b3c2d1e-1a2b3c4d5e.zip"},
+		{"debug", "4.1.1", "debug-npm-4.1.1-0d7bb8d3b1.zip"},
+		{"send", "0.16.3", ""},
+		{"package", "1.0.0", ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name+":"+testCase.version, func(t *testing.T) {
+			archivePath, err := locateCachedPackage(testCase.name, testCase.version)
+			if testCase.expectedFileName == "" {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, filepath.Join(cacheFolder, testCase.expectedFileName), archivePath)
+		})
+	}
+}
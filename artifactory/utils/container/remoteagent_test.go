@@ -0,0 +1,29 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/jfrog/jfrog-client-go/artifactory/services/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsVerifiedManifest(t *testing.T) {
+	manifest := &utils.ResultItem{
+		Repo:       "docker-local",
+		Path:       "hello-world/latest",
+		Name:       ManifestJsonFile,
+		Properties: []utils.Property{{Key: "docker.manifest.digest", Value: "sha256:12345"}},
+	}
+	builder := &RemoteAgentBuildInfoBuilder{buildInfoBuilder: &buildInfoBuilder{image: NewImage("hello-world:latest")}}
+
+	builder.manifestSha2 = "sha256:12345"
+	assert.NoError(t, builder.isVerifiedManifest(manifest))
+
+	builder.manifestSha2 = "sha256:67890"
+	assert.ErrorContains(t, builder.isVerifiedManifest(manifest), `Expects digest "sha256:67890" found "sha256:12345`)
+}
+
+func TestGetFatManifestRoot(t *testing.T) {
+	assert.Equal(t, "docker-local/hello-world", getFatManifestRoot("docker-local/hello-world/latest"))
+	assert.Equal(t, "docker-local/hello-world", getFatManifestRoot("docker-local/hello-world/latest/"))
+}
@@ -10,7 +10,9 @@ func (config *NpmConfig) GetCmd() *exec.Cmd {
 	cmd = append(cmd, config.Npm)
 	cmd = append(cmd, config.Command...)
 	cmd = append(cmd, config.CommandFlags...)
-	return exec.Command(cmd[0], cmd[1:]...)
+	command := exec.Command(cmd[0], cmd[1:]...)
+	command.Dir = config.Dir
+	return command
 }
 
 func (config *NpmConfig) GetEnv() map[string]string {
@@ -31,4 +33,6 @@ type NpmConfig struct {
 	CommandFlags []string
 	StrWriter    io.WriteCloser
 	ErrWriter    io.WriteCloser
+	// The directory to run the command in. If empty, the command runs in the current working directory.
+	Dir string
 }
@@ -9,7 +9,13 @@ import (
 
 // This method runs "npm config get" command and returns the value of the specified npm configuration.
 func ConfigGet(npmFlags []string, confName, executablePath string) (string, error) {
+	return ConfigGetInDir(npmFlags, confName, executablePath, "")
+}
+
+// ConfigGetInDir is like ConfigGet, but runs the command in the given directory, so the .npmrc file of the project in that directory is taken into account.
+func ConfigGetInDir(npmFlags []string, confName, executablePath, dir string) (string, error) {
 	configGetCmdConfig := createConfigGetCmdConfig(executablePath, confName, npmFlags)
+	configGetCmdConfig.Dir = dir
 	output, err := gofrogcmd.RunCmdOutput(configGetCmdConfig)
 	if err != nil {
 		return "", errorutils.CheckError(err)
@@ -10,6 +10,11 @@ import (
 // This method runs "npm c ls" command and returns the current npm configuration (calculated by all flags and .npmrc files).
 // For more info see https://docs.npmjs.com/cli/config
 func GetConfigList(npmFlags []string, executablePath string) (data []byte, err error) {
+	return GetConfigListInDir(npmFlags, executablePath, "")
+}
+
+// GetConfigListInDir is like GetConfigList, but runs the command in the given directory, so the .npmrc file of the project in that directory is taken into account.
+func GetConfigListInDir(npmFlags []string, executablePath, dir string) (data []byte, err error) {
 	pipeReader, pipeWriter := io.Pipe()
 	defer func(pipeReader *io.PipeReader) {
 		err = errors.Join(err, pipeReader.Close())
@@ -17,6 +22,7 @@ func GetConfigList(npmFlags []string, executablePath string) (data []byte, err e
 
 	npmFlags = append(npmFlags, "--json=false")
 	configListCmdConfig := createConfigListCmdConfig(executablePath, npmFlags, pipeWriter)
+	configListCmdConfig.Dir = dir
 	npmErrorChan := make(chan error, 1)
 	go func() {
 		npmErrorChan <- gofrogcmd.RunCmd(configListCmdConfig)
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	"github.com/jfrog/jfrog-client-go/auth/cert"
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// The ALL_PROXY environment variable sets the proxy of the requests of all schemes, and is commonly used to set a SOCKS5 proxy,
+// such as socks5://proxy.example.com:1080. Unlike HTTP_PROXY and HTTPS_PROXY, it isn't supported by the default HTTP client of jfrog-client-go.
+const allProxyEnv = "ALL_PROXY"
+
+// Returns the value of the ALL_PROXY environment variable, or of its lowercase form.
+func getAllProxy() string {
+	if allProxy := os.Getenv(allProxyEnv); allProxy != "" {
+		return allProxy
+	}
+	return os.Getenv("all_proxy")
+}
+
+// Returns the proxy of each request according to the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables,
+// with the given proxy used for the schemes whose proxy isn't set.
+func proxyFromEnvironmentWithAllProxy(allProxy string) func(*http.Request) (*url.URL, error) {
+	proxyConfig := httpproxy.FromEnvironment()
+	if proxyConfig.HTTPProxy == "" {
+		proxyConfig.HTTPProxy = allProxy
+	}
+	if proxyConfig.HTTPSProxy == "" {
+		proxyConfig.HTTPSProxy = allProxy
+	}
+	proxyFunc := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// CreateAllProxyHttpClient creates an HTTP client, which sends the requests through the proxy set in the ALL_PROXY environment variable,
+// such as a SOCKS5 proxy, unless HTTP_PROXY, HTTPS_PROXY or NO_PROXY set otherwise.
+// Returns nil if ALL_PROXY isn't set, in which case the default HTTP client of jfrog-client-go should be used.
+func CreateAllProxyHttpClient(certsPath string, insecureTls bool, clientCertPath, clientCertKeyPath string, timeout time.Duration) (*http.Client, error) {
+	allProxy := getAllProxy()
+	if allProxy == "" {
+		return nil, nil
+	}
+	// The same settings as the default transport of jfrog-client-go.
+	transport := &http.Transport{
+		Proxy: proxyFromEnvironmentWithAllProxy(allProxy),
+		DialContext: (&net.Dialer{
+			KeepAlive: 20 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if certsPath == "" {
+		//#nosec G402 -- Insecure TLS allowed here.
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: insecureTls}
+	} else {
+		var err error
+		if transport, err = cert.GetTransportWithLoadedCert(certsPath, insecureTls, transport); err != nil {
+			return nil, errorutils.CheckErrorf("failed creating the HTTP client: %s", err.Error())
+		}
+	}
+	if clientCertPath != "" {
+		certificate, err := cert.LoadCertificate(clientCertPath, clientCertKeyPath)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// CreateAllProxyHttpClientForServer creates the HTTP client of CreateAllProxyHttpClient, with the certificates, insecure TLS and client certificate
+// settings of the given server. Returns nil if ALL_PROXY isn't set.
+func CreateAllProxyHttpClientForServer(serverDetails *config.ServerDetails, timeout time.Duration) (*http.Client, error) {
+	if getAllProxy() == "" {
+		return nil, nil
+	}
+	certsPath, err := coreutils.GetJfrogCertsDir()
+	if err != nil {
+		return nil, err
+	}
+	return CreateAllProxyHttpClient(certsPath, serverDetails.InsecureTls, serverDetails.ClientCertPath, serverDetails.ClientCertKeyPath, timeout)
+}
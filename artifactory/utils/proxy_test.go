@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyFromEnvironmentWithAllProxy(t *testing.T) {
+	testCases := []struct {
+		name          string
+		httpsProxy    string
+		noProxy       string
+		requestUrl    string
+		expectedProxy string
+	}{
+		{name: "all proxy", requestUrl: "https://acme.jfrog.io/artifactory", expectedProxy: "socks5://proxy.example.com:1080"},
+		{name: "http request", requestUrl: "http://acme.jfrog.io/artifactory", expectedProxy: "socks5://proxy.example.com:1080"},
+		{name: "https proxy takes precedence", httpsProxy: "http://https-proxy.example.com:8080", requestUrl: "https://acme.jfrog.io/artifactory", expectedProxy: "http://https-proxy.example.com:8080"},
+		{name: "excluded host", noProxy: "acme.jfrog.io", requestUrl: "https://acme.jfrog.io/artifactory"},
+		{name: "localhost", requestUrl: "https://localhost:8081/artifactory"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			for _, env := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy"} {
+				t.Setenv(env, "")
+			}
+			t.Setenv("HTTPS_PROXY", testCase.httpsProxy)
+			t.Setenv("NO_PROXY", testCase.noProxy)
+			req, err := http.NewRequest(http.MethodGet, testCase.requestUrl, nil)
+			assert.NoError(t, err)
+			proxyUrl, err := proxyFromEnvironmentWithAllProxy("socks5://proxy.example.com:1080")(req)
+			assert.NoError(t, err)
+			if testCase.expectedProxy == "" {
+				assert.Nil(t, proxyUrl)
+			} else if assert.NotNil(t, proxyUrl) {
+				assert.Equal(t, testCase.expectedProxy, proxyUrl.String())
+			}
+		})
+	}
+}
+
+func TestCreateAllProxyHttpClient(t *testing.T) {
+	t.Setenv(allProxyEnv, "")
+	t.Setenv("all_proxy", "")
+	httpClient, err := CreateAllProxyHttpClient("", false, "", "", 0)
+	assert.NoError(t, err)
+	assert.Nil(t, httpClient)
+
+	t.Setenv("all_proxy", "socks5://proxy.example.com:1080")
+	httpClient, err = CreateAllProxyHttpClient("", false, "", "", 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, httpClient)
+}
+
+func TestCreateServiceManagerIgnoresAllProxy(t *testing.T) {
+	t.Setenv(coreutils.HomeDir, t.TempDir())
+	t.Setenv(allProxyEnv, "socks5://proxy.example.com:1080")
+	servicesManager, err := CreateServiceManager(&config.ServerDetails{ArtifactoryUrl: "https://acme.jfrog.io/artifactory/"}, -1, 0, false)
+	assert.NoError(t, err)
+	// The default services manager keeps the HTTP client of jfrog-client-go, whose transport doesn't read ALL_PROXY.
+	assert.Nil(t, servicesManager.GetConfig().GetHttpClient())
+	transport, ok := servicesManager.Client().GetHttpClient().GetClient().Transport.(*http.Transport)
+	if assert.True(t, ok) {
+		req, err := http.NewRequest(http.MethodGet, "https://acme.jfrog.io/artifactory/api/system/ping", nil)
+		assert.NoError(t, err)
+		proxyUrl, err := transport.Proxy(req)
+		assert.NoError(t, err)
+		assert.Nil(t, proxyUrl)
+	}
+}
+
+func TestCreateAllProxyHttpClientForServer(t *testing.T) {
+	t.Setenv(coreutils.HomeDir, t.TempDir())
+	t.Setenv(allProxyEnv, "")
+	t.Setenv("all_proxy", "")
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: "https://acme.jfrog.io/artifactory/", InsecureTls: true}
+	httpClient, err := CreateAllProxyHttpClientForServer(serverDetails, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, httpClient)
+
+	t.Setenv(allProxyEnv, "socks5://proxy.example.com:1080")
+	httpClient, err = CreateAllProxyHttpClientForServer(serverDetails, 0)
+	assert.NoError(t, err)
+	if assert.NotNil(t, httpClient) {
+		assert.True(t, httpClient.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+	}
+}
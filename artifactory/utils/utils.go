@@ -32,6 +32,7 @@ import (
 	clientUtils "github.com/jfrog/jfrog-client-go/utils"
 	"github.com/jfrog/jfrog-client-go/utils/errorutils"
 	ioUtils "github.com/jfrog/jfrog-client-go/utils/io"
+	"github.com/jfrog/jfrog-client-go/utils/io/httputils"
 )
 
 func GetProjectDir(global bool) (string, error) {
@@ -101,15 +102,52 @@ func CreateServiceManagerWithThreads(serverDetails *config.ServerDetails, isDryR
 	return CreateServiceManagerWithContext(context.Background(), serverDetails, isDryRun, threads, httpRetries, httpRetryWaitMilliSecs, 0)
 }
 
-func CreateServiceManagerWithContext(context context.Context, serverDetails *config.ServerDetails, isDryRun bool, threads, httpRetries, httpRetryWaitMilliSecs int, timeout time.Duration) (artifactory.ArtifactoryServicesManager, error) {
-	certsPath, err := coreutils.GetJfrogCertsDir()
+// Create a service manager, which identifies itself with the given user-agent.
+// If the given user-agent is empty, the jfrog-cli-core name and version are used.
+func CreateServiceManagerWithUserAgent(serverDetails *config.ServerDetails, httpRetries, httpRetryWaitMilliSecs int, isDryRun bool, userAgent string) (artifactory.ArtifactoryServicesManager, error) {
+	artAuth, err := createArtAuthWithUserAgent(serverDetails, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return createServiceManagerWithAuth(context.Background(), serverDetails, artAuth, nil, isDryRun, 0, httpRetries, httpRetryWaitMilliSecs, 0)
+}
+
+// Create a service manager, which sends its requests using the given HTTP client, and identifies itself with the given user-agent.
+// This allows replacing the HTTP transport, for example, with a mock transport in tests. If the given HTTP client is nil, the default one is used.
+func CreateServiceManagerWithHttpClient(context context.Context, serverDetails *config.ServerDetails, httpClient *http.Client, httpRetries, httpRetryWaitMilliSecs int, timeout time.Duration, userAgent string) (artifactory.ArtifactoryServicesManager, error) {
+	artAuth, err := createArtAuthWithUserAgent(serverDetails, userAgent)
 	if err != nil {
 		return nil, err
 	}
+	return createServiceManagerWithAuth(context, serverDetails, artAuth, httpClient, false, 0, httpRetries, httpRetryWaitMilliSecs, timeout)
+}
+
+// If the given user-agent is empty, the jfrog-cli-core name and version are used.
+func createArtAuthWithUserAgent(serverDetails *config.ServerDetails, userAgent string) (auth.ServiceDetails, error) {
 	artAuth, err := serverDetails.CreateArtAuthConfig()
 	if err != nil {
 		return nil, err
 	}
+	userAgentInterceptor := coreutils.CreateUserAgentInterceptor(userAgent)
+	artAuth.AppendPreRequestFunction(func(_ *auth.CommonConfigFields, httpClientDetails *httputils.HttpClientDetails) error {
+		return userAgentInterceptor(httpClientDetails)
+	})
+	return artAuth, nil
+}
+
+func CreateServiceManagerWithContext(context context.Context, serverDetails *config.ServerDetails, isDryRun bool, threads, httpRetries, httpRetryWaitMilliSecs int, timeout time.Duration) (artifactory.ArtifactoryServicesManager, error) {
+	artAuth, err := serverDetails.CreateArtAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+	return createServiceManagerWithAuth(context, serverDetails, artAuth, nil, isDryRun, threads, httpRetries, httpRetryWaitMilliSecs, timeout)
+}
+
+func createServiceManagerWithAuth(context context.Context, serverDetails *config.ServerDetails, artAuth auth.ServiceDetails, httpClient *http.Client, isDryRun bool, threads, httpRetries, httpRetryWaitMilliSecs int, timeout time.Duration) (artifactory.ArtifactoryServicesManager, error) {
+	certsPath, err := coreutils.GetJfrogCertsDir()
+	if err != nil {
+		return nil, err
+	}
 	configBuilder := clientConfig.NewConfigBuilder().
 		SetServiceDetails(artAuth).
 		SetCertificatesPath(certsPath).
@@ -126,6 +164,9 @@ func CreateServiceManagerWithContext(context context.Context, serverDetails *con
 	if timeout > 0 {
 		configBuilder.SetOverallRequestTimeout(timeout)
 	}
+	if httpClient != nil {
+		configBuilder.SetHttpClient(httpClient)
+	}
 	serviceConfig, err := configBuilder.Build()
 	if err != nil {
 		return nil, err
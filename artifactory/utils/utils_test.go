@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	jfrogclicore "github.com/jfrog/jfrog-cli-core/v2"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
 	"net/http"
 	"net/http/httptest"
@@ -61,3 +62,29 @@ func testGetRtMajorVersion(t *testing.T, version string, expected int) {
 	assert.NoError(t, err)
 	assert.Equal(t, expected, major)
 }
+
+func TestCreateServiceManagerWithUserAgent(t *testing.T) {
+	testCases := []struct {
+		userAgent         string
+		expectedUserAgent string
+	}{
+		{"", jfrogclicore.GetUserAgent()},
+		{"my-agent/1.0.0", "my-agent/1.0.0"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.expectedUserAgent, func(t *testing.T) {
+			var userAgent string
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userAgent = r.Header.Get("User-Agent")
+				_, err := w.Write([]byte(`{"version": "7.6.5"}`))
+				assert.NoError(t, err)
+			}))
+			defer testServer.Close()
+			serviceManager, err := CreateServiceManagerWithUserAgent(&config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}, -1, 0, false, testCase.userAgent)
+			assert.NoError(t, err)
+			_, err = GetRtMajorVersion(serviceManager)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedUserAgent, userAgent)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 
 	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
@@ -50,6 +51,7 @@ type ConfigType string
 
 const (
 	YAML       ConfigType = "yaml"
+	JSON       ConfigType = "json"
 	PROPERTIES ConfigType = "properties"
 )
 
@@ -231,6 +233,25 @@ func ReadConfigFile(configPath string, configType ConfigType) (config *viper.Vip
 	return config, errorutils.CheckError(err)
 }
 
+// DetectConfigType returns the type of the given YAML or JSON config file, by its extension.
+// If the extension is neither '.json', '.yaml' nor '.yml', a file whose content starts with '{' is considered JSON, and any other file YAML.
+func DetectConfigType(configPath string) (ConfigType, error) {
+	switch strings.ToLower(filepath.Ext(configPath)) {
+	case ".json":
+		return JSON, nil
+	case ".yaml", ".yml":
+		return YAML, nil
+	}
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", errorutils.CheckError(err)
+	}
+	if strings.HasPrefix(strings.TrimSpace(string(content)), "{") {
+		return JSON, nil
+	}
+	return YAML, nil
+}
+
 func ReadResolutionOnlyConfiguration(confFilePath string) (*RepositoryConfig, error) {
 	log.Debug("Preparing to read the config file", confFilePath)
 	vConfig, err := ReadConfigFile(confFilePath, YAML)
@@ -1,8 +1,11 @@
 package project
 
 import (
-	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestFromString(t *testing.T) {
@@ -28,3 +31,30 @@ func TestFromString(t *testing.T) {
 	result := FromString("InvalidProject")
 	assert.Equal(t, ProjectType(-1), result)
 }
+
+func TestDetectConfigType(t *testing.T) {
+	testCases := []struct {
+		fileName     string
+		content      string
+		expectedType ConfigType
+	}{
+		{fileName: "yarn.yaml", content: "version: 1", expectedType: YAML},
+		{fileName: "yarn.YML", content: "version: 1", expectedType: YAML},
+		{fileName: "yarn.json", content: `{"version": 1}`, expectedType: JSON},
+		// Without a known extension, the type is detected by the content.
+		{fileName: "yarn.conf", content: "  \n{\"version\": 1}", expectedType: JSON},
+		{fileName: "yarn.conf", content: "version: 1", expectedType: YAML},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.fileName, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), testCase.fileName)
+			assert.NoError(t, os.WriteFile(configPath, []byte(testCase.content), 0644))
+			configType, err := DetectConfigType(configPath)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedType, configType)
+		})
+	}
+
+	_, err := DetectConfigType(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -13,9 +13,11 @@ import (
 	"runtime"
 	"strings"
 
+	jfrogclicore "github.com/jfrog/jfrog-cli-core/v2"
 	"github.com/jfrog/jfrog-client-go/utils"
 	"github.com/jfrog/jfrog-client-go/utils/errorutils"
 	"github.com/jfrog/jfrog-client-go/utils/io/fileutils"
+	"github.com/jfrog/jfrog-client-go/utils/io/httputils"
 	"github.com/jfrog/jfrog-client-go/utils/log"
 )
 
@@ -34,6 +36,16 @@ const (
 	// DeprecatedExtractorsRemoteEnv is deprecated, it is replaced with ReleasesRemoteEnv.
 	// Its functionality was similar to ReleasesRemoteEnv, but it proxies releases.jfrog.io/artifactory/oss-release-local instead.
 	DeprecatedExtractorsRemoteEnv = "JFROG_CLI_EXTRACTORS_REMOTE"
+	// FailOnDeprecatedExtractorsRemoteEnv makes downloading the CLI dependencies fail if DeprecatedExtractorsRemoteEnv is set, instead of only warning about it.
+	// It allows enforcing the migration to ReleasesRemoteEnv.
+	FailOnDeprecatedExtractorsRemoteEnv = "JFROG_CLI_FAIL_ON_DEPRECATED_EXTRACTORS_REMOTE"
+	// MavenExtractorRemoteEnv and GradleExtractorRemoteEnv are similar to ReleasesRemoteEnv, but apply only to downloading the Maven or Gradle extractor jars.
+	// They allow downloading each extractor through a different remote repository, and take precedence over ReleasesRemoteEnv.
+	MavenExtractorRemoteEnv  = "JFROG_CLI_MAVEN_EXTRACTOR_REMOTE"
+	GradleExtractorRemoteEnv = "JFROG_CLI_GRADLE_EXTRACTOR_REMOTE"
+	// ExtractorDownloadTimeoutEnv sets the timeout, in seconds, of each HTTP request made while downloading the CLI dependencies (extractor jars etc.).
+	// By default, there's no timeout.
+	ExtractorDownloadTimeoutEnv = "JFROG_CLI_EXTRACTOR_DOWNLOAD_TIMEOUT"
 	// JFrog releases URL
 	JfrogReleasesUrl = "https://releases.jfrog.io/artifactory/"
 )
@@ -296,6 +308,22 @@ type Credentials interface {
 	GetPassword() string
 }
 
+// RetryConfig configures the retries of failed HTTP requests.
+type RetryConfig struct {
+	// The maximum number of retries of a failed request. If negative, the HTTP client's default is used.
+	MaxRetries int
+	// The time to wait between retries, in milliseconds.
+	WaitMs int
+}
+
+// GetRetries returns the configured number of retries and wait time, or -1 and 0 (the HTTP client's defaults) if the config is nil.
+func (rc *RetryConfig) GetRetries() (maxRetries, waitMs int) {
+	if rc == nil {
+		return -1, 0
+	}
+	return rc.MaxRetries, rc.WaitMs
+}
+
 func ReplaceVars(content []byte, specVars map[string]string) []byte {
 	log.Debug("Replacing variables in the provided content: \n" + string(content))
 	for key, val := range specVars {
@@ -531,6 +559,21 @@ func GetCliUserAgent() string {
 	return fmt.Sprintf("%s/%s", cliUserAgentName, cliUserAgentVersion)
 }
 
+// CreateUserAgentInterceptor returns a pre-request interceptor, which sets the User-Agent header of the requests to the given user-agent.
+// If the given user-agent is empty, the jfrog-cli-core name and version are used.
+func CreateUserAgentInterceptor(userAgent string) func(*httputils.HttpClientDetails) error {
+	if userAgent == "" {
+		userAgent = jfrogclicore.GetUserAgent()
+	}
+	return func(httpClientDetails *httputils.HttpClientDetails) error {
+		if httpClientDetails.Headers == nil {
+			httpClientDetails.Headers = make(map[string]string)
+		}
+		httpClientDetails.Headers["User-Agent"] = userAgent
+		return nil
+	}
+}
+
 func SetCliUserAgentName(cliUserAgentNameToSet string) {
 	cliUserAgentName = cliUserAgentNameToSet
 }
@@ -0,0 +1,142 @@
+package dependencies
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	ioutils "github.com/jfrog/jfrog-client-go/utils/io"
+)
+
+// DownloadProgressReporter is notified of the progress of the extractor downloads.
+// Since several extractors may be downloaded concurrently, the reporter's methods may be called from several goroutines.
+type DownloadProgressReporter interface {
+	// AddTotal is called when a download starts, with the size of the downloaded file in bytes (-1 if the size is unknown).
+	AddTotal(bytes int64)
+	// AddDownloaded is called as the downloaded bytes are written.
+	AddDownloaded(bytes int64)
+}
+
+var (
+	progressReporter     DownloadProgressReporter
+	progressReporterLock sync.RWMutex
+)
+
+// SetDownloadProgressReporter sets the reporter notified of the progress of all the extractor downloads, allowing front-ends to show a unified progress bar.
+// Pass nil to stop reporting the progress.
+func SetDownloadProgressReporter(reporter DownloadProgressReporter) {
+	progressReporterLock.Lock()
+	defer progressReporterLock.Unlock()
+	progressReporter = reporter
+}
+
+func getDownloadProgressReporter() DownloadProgressReporter {
+	progressReporterLock.RLock()
+	defer progressReporterLock.RUnlock()
+	return progressReporter
+}
+
+// AggregatedDownloadProgress is a thread-safe DownloadProgressReporter, which sums up the downloaded bytes and the sizes of all the downloads.
+type AggregatedDownloadProgress struct {
+	total      atomic.Int64
+	downloaded atomic.Int64
+	// If set, called with the cumulative downloaded bytes and total bytes on each update.
+	onUpdate func(downloaded, total int64)
+}
+
+// NewAggregatedDownloadProgress creates an aggregated progress reporter. The given function, if not nil, is called on each update.
+func NewAggregatedDownloadProgress(onUpdate func(downloaded, total int64)) *AggregatedDownloadProgress {
+	return &AggregatedDownloadProgress{onUpdate: onUpdate}
+}
+
+func (adp *AggregatedDownloadProgress) AddTotal(bytes int64) {
+	// Downloads of an unknown size don't change the total.
+	if bytes > 0 {
+		adp.total.Add(bytes)
+	}
+	adp.notify()
+}
+
+func (adp *AggregatedDownloadProgress) AddDownloaded(bytes int64) {
+	adp.downloaded.Add(bytes)
+	adp.notify()
+}
+
+// Downloaded returns the number of bytes downloaded so far by all the downloads.
+func (adp *AggregatedDownloadProgress) Downloaded() int64 {
+	return adp.downloaded.Load()
+}
+
+// Total returns the sum of the sizes of all the downloads started so far.
+func (adp *AggregatedDownloadProgress) Total() int64 {
+	return adp.total.Load()
+}
+
+func (adp *AggregatedDownloadProgress) notify() {
+	if adp.onUpdate != nil {
+		adp.onUpdate(adp.downloaded.Load(), adp.total.Load())
+	}
+}
+
+// Adapts a DownloadProgressReporter to the progress manager of the HTTP client, which reports the progress of each downloaded file separately.
+// Only the methods used by file downloads report the progress. The others are no-ops.
+type reporterProgressMgr struct {
+	reporter DownloadProgressReporter
+}
+
+// Returns the progress manager which reports the download progress to the configured reporter, or nil if no reporter is configured.
+func getDownloadProgressMgr() ioutils.ProgressMgr {
+	reporter := getDownloadProgressReporter()
+	if reporter == nil {
+		return nil
+	}
+	return &reporterProgressMgr{reporter: reporter}
+}
+
+func (rpm *reporterProgressMgr) NewProgressReader(total int64, _, _ string) ioutils.Progress {
+	rpm.reporter.AddTotal(total)
+	return &reporterProgress{reporter: rpm.reporter}
+}
+
+func (rpm *reporterProgressMgr) SetMergingState(int, bool) ioutils.Progress {
+	return &reporterProgress{reporter: rpm.reporter}
+}
+
+func (rpm *reporterProgressMgr) GetProgress(int) ioutils.Progress {
+	return &reporterProgress{reporter: rpm.reporter}
+}
+
+func (rpm *reporterProgressMgr) RemoveProgress(int)              {}
+func (rpm *reporterProgressMgr) IncrementGeneralProgress()       {}
+func (rpm *reporterProgressMgr) Quit() error                     { return nil }
+func (rpm *reporterProgressMgr) IncGeneralProgressTotalBy(int64) {}
+func (rpm *reporterProgressMgr) SetHeadlineMsg(string)           {}
+func (rpm *reporterProgressMgr) ClearHeadlineMsg()               {}
+func (rpm *reporterProgressMgr) InitProgressReaders()            {}
+func (rpm *reporterProgressMgr) ClearProgress()                  {}
+
+type reporterProgress struct {
+	reporter DownloadProgressReporter
+}
+
+func (rp *reporterProgress) ActionWithProgress(reader io.Reader) io.Reader {
+	return &reportingReader{reader: reader, reporter: rp.reporter}
+}
+
+func (rp *reporterProgress) SetProgress(int64) {}
+func (rp *reporterProgress) Abort()            {}
+func (rp *reporterProgress) GetId() int        { return 0 }
+
+// A reader which reports the number of bytes read from it.
+type reportingReader struct {
+	reader   io.Reader
+	reporter DownloadProgressReporter
+}
+
+func (rr *reportingReader) Read(p []byte) (n int, err error) {
+	n, err = rr.reader.Read(p)
+	if n > 0 {
+		rr.reporter.AddDownloaded(int64(n))
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package dependencies
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadDependencyWithProgressReporter(t *testing.T) {
+	extractorContent := []byte(strings.Repeat("extractor-jar-content", 1000))
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(len(extractorContent)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, err := w.Write(extractorContent)
+			assert.NoError(t, err)
+		}
+	}))
+	defer testServer.Close()
+
+	var updatesLock sync.Mutex
+	var lastDownloaded int64
+	progress := NewAggregatedDownloadProgress(func(downloaded, total int64) {
+		updatesLock.Lock()
+		defer updatesLock.Unlock()
+		lastDownloaded = max(lastDownloaded, downloaded)
+	})
+	SetDownloadProgressReporter(progress)
+	defer SetDownloadProgressReporter(nil)
+
+	const downloadsCount = 5
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+	tempDir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < downloadsCount; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			jarName := fmt.Sprintf("build-info-extractor-maven3-2.0.%d-uber.jar", i)
+			assert.NoError(t, DownloadDependency(serverDetails, "oss-release-local/"+jarName, filepath.Join(tempDir, jarName), false))
+		}(i)
+	}
+	wg.Wait()
+
+	expectedTotal := int64(downloadsCount * len(extractorContent))
+	assert.Equal(t, expectedTotal, progress.Total())
+	assert.Equal(t, expectedTotal, progress.Downloaded())
+	assert.Equal(t, expectedTotal, lastDownloaded)
+}
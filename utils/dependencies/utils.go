@@ -1,15 +1,28 @@
 package dependencies
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	biutils "github.com/jfrog/build-info-go/utils"
+	"github.com/jfrog/jfrog-cli-core/v2/artifactory/utils"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/lock"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/osutils"
 	"github.com/jfrog/jfrog-client-go/http/httpclient"
 	"github.com/jfrog/jfrog-client-go/http/jfroghttpclient"
 	"github.com/jfrog/jfrog-client-go/utils/errorutils"
@@ -21,6 +34,8 @@ import (
 const (
 	ChecksumFileName  = "checksum.sha2"
 	jarsDocumentation = "https://docs.jfrog-applications.jfrog.io/jfrog-applications/jfrog-cli/cli-for-jfrog-artifactory/package-managers-integration#downloading-the-maven-and-gradle-extractor-jars"
+	// The directory, under the extractor's local directory, which holds the lock files of the extractor downloads.
+	extractorLocksDirName = "locks"
 )
 
 // Download the relevant build-info-extractor jar.
@@ -29,12 +44,189 @@ const (
 // targetPath: The local download path (without the file name).
 // downloadPath: Artifactory download path.
 func DownloadExtractor(targetPath, downloadPath string) error {
+	return DownloadExtractorWithOptions(targetPath, downloadPath, ExtractorDownloadOptions{})
+}
+
+// ExtractorDownloadOptions determine when an extractor which already exists in the local path is downloaded again, and how it's downloaded.
+// By default, an existing extractor is never downloaded again.
+type ExtractorDownloadOptions struct {
+	// If positive, an existing extractor which was downloaded longer than MaxAge ago is downloaded again.
+	MaxAge time.Duration
+	// If set, an existing extractor of a different version is downloaded again. The version of the existing extractor is the version
+	// requested when it was downloaded, or if it's unknown, the version in its file name.
+	Version string
+	// The user-agent with which the download requests identify themselves. If empty, the jfrog-cli-core name and version are used.
+	UserAgent string
+	// The timeout of each request sent while downloading the extractor. If zero, the timeout set by the
+	// JFROG_CLI_EXTRACTOR_DOWNLOAD_TIMEOUT environment variable is used, and if it isn't set, the requests have no timeout.
+	RequestTimeout time.Duration
+	// If positive, the timeout of the whole download, including waiting for another process which downloads the extractor, and the retries.
+	Timeout time.Duration
+}
+
+// Same as DownloadExtractor, but an extractor which already exists in the local path may be downloaded again, according to the options.
+func DownloadExtractorWithOptions(targetPath, downloadPath string, options ExtractorDownloadOptions) error {
+	return DownloadExtractorWithContext(context.Background(), targetPath, downloadPath, options)
+}
+
+// Same as DownloadExtractorWithOptions, but the download is aborted when the given context is canceled (for example, on the build's timeout).
+// On cancellation, the partially downloaded file is removed, and an error wrapping the context's error is returned.
+func DownloadExtractorWithContext(ctx context.Context, targetPath, downloadPath string, options ExtractorDownloadOptions) error {
 	artDetails, remotePath, err := GetExtractorsRemoteDetails(downloadPath)
 	if err != nil {
 		return err
 	}
 
-	return DownloadDependency(artDetails, remotePath, targetPath, false)
+	return downloadExtractorWithLock(ctx, artDetails, remotePath, targetPath, options)
+}
+
+// Several processes (such as concurrent Maven or Gradle builds) may try to download the same extractor to the same local path.
+// To avoid racing on the downloaded file, the download is done while holding a lock in the extractor's local directory.
+// Processes waiting for the lock use the extractor downloaded by the process which held it.
+func downloadExtractorWithLock(ctx context.Context, artDetails *config.ServerDetails, remotePath, targetPath string, options ExtractorDownloadOptions) (err error) {
+	if options.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, options.Timeout)
+		defer cancelTimeout()
+	}
+	lockWait, err := getExtractorLockWait(options.RequestTimeout)
+	if err != nil {
+		return
+	}
+	lockCtx, cancel := context.WithTimeout(ctx, lockWait)
+	defer cancel()
+	unlockFunc, err := lock.CreateLockWithContext(lockCtx, filepath.Join(filepath.Dir(targetPath), extractorLocksDirName))
+	// Defer the lockFile.Unlock() function before throwing a possible error to avoid deadlock situations.
+	defer func() {
+		err = errors.Join(err, unlockFunc())
+	}()
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		// The process holding the lock may have downloaded the extractor by now, even if it still holds the lock.
+		if upToDate, checkErr := isExtractorUpToDate(targetPath, options); checkErr == nil && upToDate {
+			log.Debug("The extractor's lock wasn't acquired, but the extractor was already downloaded to", targetPath)
+			err = nil
+		}
+		return
+	}
+	upToDate, err := isExtractorUpToDate(targetPath, options)
+	if err != nil || upToDate {
+		return
+	}
+	if err = downloadDependency(ctx, artDetails, remotePath, targetPath, false, options); err != nil || options.Version == "" {
+		return
+	}
+	return errorutils.CheckError(os.WriteFile(getExtractorVersionFilePath(targetPath), []byte(options.Version), 0644))
+}
+
+// The minimal time to wait for the extractor's lock, which another process holds while it downloads the extractor.
+const defaultExtractorLockWait = 2 * time.Minute
+
+// Returns the time to wait for the extractor's lock, which is at least as long as the download of the process holding it may take.
+// A variable, to allow shortening the wait in tests.
+var getExtractorLockWait = func(requestTimeout time.Duration) (time.Duration, error) {
+	timeout, err := getRequestTimeout(requestTimeout)
+	if err != nil {
+		return 0, err
+	}
+	// The download of the process holding the lock sends two requests, the file details and the download itself,
+	// each of which may take up to the request timeout on every attempt.
+	maxRetries, _ := getDownloadRetryConfig().GetRetries()
+	return max(defaultExtractorLockWait, 2*time.Duration(max(maxRetries, 0)+1)*timeout), nil
+}
+
+// Returns true if the extractor exists in the local path, and doesn't need to be downloaded again according to the options.
+func isExtractorUpToDate(targetPath string, options ExtractorDownloadOptions) (bool, error) {
+	fileInfo, err := os.Stat(targetPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errorutils.CheckError(err)
+	}
+	if options.MaxAge > 0 && time.Since(fileInfo.ModTime()) > options.MaxAge {
+		log.Debug(fmt.Sprintf("The extractor %s was downloaded more than %s ago. Downloading it again...", targetPath, options.MaxAge))
+		return false, nil
+	}
+	if options.Version != "" {
+		if existingVersion := getExistingExtractorVersion(targetPath); existingVersion != options.Version {
+			log.Debug(fmt.Sprintf("The extractor %s is of version '%s' rather than '%s'. Downloading it again...", targetPath, existingVersion, options.Version))
+			return false, nil
+		}
+	}
+	log.Debug("The extractor already exists in", targetPath)
+	return true, nil
+}
+
+// Returns the version requested when the extractor was downloaded, or the version in its file name if it's unknown.
+func getExistingExtractorVersion(targetPath string) string {
+	if version, err := os.ReadFile(getExtractorVersionFilePath(targetPath)); err == nil {
+		return strings.TrimSpace(string(version))
+	}
+	if match := extractorVersionRegexp.FindStringSubmatch(filepath.Base(targetPath)); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// The file which holds the version requested when the extractor was downloaded.
+func getExtractorVersionFilePath(targetPath string) string {
+	return targetPath + ".version"
+}
+
+// ExtractorInfo describes a build-info extractor jar cached locally.
+type ExtractorInfo struct {
+	// The jar's file name.
+	Name string
+	// The jar's absolute path.
+	Path string
+	// The jar's size in bytes.
+	Size int64
+	// The extractor's version, as parsed from the file name. Empty if the file name doesn't include a version.
+	Version string
+}
+
+// Matches the version in the extractors file names, such as build-info-extractor-maven3-2.41.24-uber.jar.
+var extractorVersionRegexp = regexp.MustCompile(`-(\d+(?:\.\d+)*)(?:-uber)?\.jar$`)
+
+// ListCachedExtractors returns the extractor jars cached under the given directory (for example, the JFrog dependencies directory),
+// sorted by their paths. The lock files of the extractor downloads are ignored.
+func ListCachedExtractors(dir string) (extractors []ExtractorInfo, err error) {
+	// The paths of the walked files are relative if the directory is, so the directory is made absolute.
+	if dir, err = filepath.Abs(dir); err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if entry.Name() == extractorLocksDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(entry.Name()) != ".jar" {
+			return nil
+		}
+		fileInfo, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		extractor := ExtractorInfo{Name: entry.Name(), Path: path, Size: fileInfo.Size()}
+		if match := extractorVersionRegexp.FindStringSubmatch(entry.Name()); match != nil {
+			extractor.Version = match[1]
+		}
+		extractors = append(extractors, extractor)
+		return nil
+	})
+	if err != nil {
+		return nil, errorutils.CheckError(err)
+	}
+	sort.Slice(extractors, func(i, j int) bool { return extractors[i].Path < extractors[j].Path })
+	return
 }
 
 func CreateChecksumFile(targetPath, checksum string) (err error) {
@@ -54,8 +246,15 @@ func CreateChecksumFile(targetPath, checksum string) (err error) {
 // GetExtractorsRemoteDetails retrieves the server details necessary to download the build-info extractors from a remote repository.
 // downloadPath - specifies the path in the remote repository from which the extractors will be downloaded.
 func GetExtractorsRemoteDetails(downloadPath string) (server *config.ServerDetails, remoteRepo string, err error) {
-	// Download from the remote repository that proxies https://releases.jfrog.io
-	server, remoteRepo, err = getExtractorsRemoteDetailsFromEnv(downloadPath)
+	if err = validateDeprecatedRemoteEnvNotSet(); err != nil {
+		return
+	}
+	// Download from the remote repository configured for this extractor type, if configured
+	server, remoteRepo, err = getExtractorsRemoteDetailsFromTypeEnv(downloadPath)
+	if remoteRepo == "" && err == nil {
+		// Download from the remote repository that proxies https://releases.jfrog.io
+		server, remoteRepo, err = getExtractorsRemoteDetailsFromEnv(downloadPath)
+	}
 	if remoteRepo == "" && err == nil {
 		// Fallback to the deprecated JFROG_CLI_EXTRACTORS_REMOTE environment variable
 		server, remoteRepo, err = getExtractorsRemoteDetailsFromLegacyEnv(downloadPath)
@@ -72,6 +271,31 @@ func GetExtractorsRemoteDetails(downloadPath string) (server *config.ServerDetai
 	return &config.ServerDetails{ArtifactoryUrl: coreutils.JfrogReleasesUrl}, path.Join("oss-release-local", downloadPath), nil
 }
 
+func getExtractorsRemoteDetailsFromTypeEnv(downloadPath string) (server *config.ServerDetails, remoteRepo string, err error) {
+	remoteEnv := getExtractorTypeRemoteEnv(downloadPath)
+	if remoteEnv == "" {
+		return
+	}
+	server, remoteRepo, err = GetRemoteDetails(remoteEnv)
+	if remoteRepo != "" && err == nil {
+		remoteRepo = getFullExtractorsPathInArtifactory(remoteRepo, remoteEnv, downloadPath)
+	}
+	return
+}
+
+// Returns the environment variable which configures the remote repository of the extractor in the given download path,
+// or an empty string if the download path isn't of a Maven or Gradle extractor.
+func getExtractorTypeRemoteEnv(downloadPath string) string {
+	switch {
+	case strings.Contains(downloadPath, "build-info-extractor-maven"):
+		return coreutils.MavenExtractorRemoteEnv
+	case strings.Contains(downloadPath, "build-info-extractor-gradle"):
+		return coreutils.GradleExtractorRemoteEnv
+	default:
+		return ""
+	}
+}
+
 func getExtractorsRemoteDetailsFromEnv(downloadPath string) (server *config.ServerDetails, remoteRepo string, err error) {
 	server, remoteRepo, err = GetRemoteDetails(coreutils.ReleasesRemoteEnv)
 	if remoteRepo != "" && err == nil {
@@ -90,10 +314,28 @@ func getExtractorsRemoteDetailsFromLegacyEnv(downloadPath string) (server *confi
 	return
 }
 
+// In strict mode, fails if the deprecated JFROG_CLI_EXTRACTORS_REMOTE environment variable is set, even if it's overridden by another environment variable.
+// By default, the deprecated environment variable is still used, with a warning.
+func validateDeprecatedRemoteEnvNotSet() error {
+	strictStr := os.Getenv(coreutils.FailOnDeprecatedExtractorsRemoteEnv)
+	if strictStr == "" || os.Getenv(coreutils.DeprecatedExtractorsRemoteEnv) == "" {
+		return nil
+	}
+	strict, err := strconv.ParseBool(strictStr)
+	if err != nil {
+		return errorutils.CheckErrorf("the value of the %s environment variable must be a boolean, but got: '%s'", coreutils.FailOnDeprecatedExtractorsRemoteEnv, strictStr)
+	}
+	if !strict {
+		return nil
+	}
+	return errorutils.CheckErrorf("the deprecated %q environment variable is set. Use %q instead.\nRead more about it at %s",
+		coreutils.DeprecatedExtractorsRemoteEnv, coreutils.ReleasesRemoteEnv, jarsDocumentation)
+}
+
 // GetRemoteDetails function retrieves the server details and downloads path for the build-info extractor file.
 // serverAndRepo - the server id and the remote repository that proxies releases.jfrog.io, in form of '<ServerID>/<RemoteRepo>'.
 // downloadPath - specifies the path in the remote repository from which the extractors will be downloaded.
-// remoteEnv - the relevant environment variable that was used: releasesRemoteEnv/ExtractorsRemoteEnv.
+// remoteEnv - the relevant environment variable that was used: releasesRemoteEnv/ExtractorsRemoteEnv, or one of the extractor type specific environment variables.
 // The function returns the server that matches the given server ID, the complete path of the build-info extractor concatenated with the specified remote repository, and an error if occurred.
 func GetRemoteDetails(remoteEnv string) (server *config.ServerDetails, repoName string, err error) {
 	serverID, repoName, err := coreutils.GetServerIdAndRepo(remoteEnv)
@@ -109,10 +351,11 @@ func GetRemoteDetails(remoteEnv string) (server *config.ServerDetails, repoName
 }
 
 func getFullExtractorsPathInArtifactory(repoName, remoteEnv, downloadPath string) string {
-	if remoteEnv == coreutils.ReleasesRemoteEnv {
-		return path.Join(repoName, "artifactory", "oss-release-local", downloadPath)
+	if remoteEnv == coreutils.DeprecatedExtractorsRemoteEnv {
+		return path.Join(repoName, downloadPath)
 	}
-	return path.Join(repoName, downloadPath)
+	// The remote repository proxies https://releases.jfrog.io
+	return path.Join(repoName, "artifactory", "oss-release-local", downloadPath)
 }
 
 // Downloads the requested resource.
@@ -120,7 +363,18 @@ func getFullExtractorsPathInArtifactory(repoName, remoteEnv, downloadPath string
 // artDetails: The artifactory server details to download the resource from.
 // downloadPath: Artifactory download path.
 // targetPath: The local download path (without the file name).
-func DownloadDependency(artDetails *config.ServerDetails, downloadPath, targetPath string, shouldExplode bool) (err error) {
+func DownloadDependency(artDetails *config.ServerDetails, downloadPath, targetPath string, shouldExplode bool) error {
+	return DownloadDependencyWithContext(context.Background(), artDetails, downloadPath, targetPath, shouldExplode)
+}
+
+// Same as DownloadDependency, but the download is aborted when the given context is canceled.
+// The resource is downloaded to a temporary directory, which is removed on cancellation, so no partial file is left in the target path.
+func DownloadDependencyWithContext(ctx context.Context, artDetails *config.ServerDetails, downloadPath, targetPath string, shouldExplode bool) error {
+	return downloadDependency(ctx, artDetails, downloadPath, targetPath, shouldExplode, ExtractorDownloadOptions{})
+}
+
+// The requests identify themselves with the options' user-agent, and time out after the options' request timeout.
+func downloadDependency(ctx context.Context, artDetails *config.ServerDetails, downloadPath, targetPath string, shouldExplode bool, options ExtractorDownloadOptions) (err error) {
 	downloadUrl := artDetails.ArtifactoryUrl + downloadPath
 	log.Info("Downloading JFrog's Dependency from", downloadUrl)
 	filename, localDir := fileutils.GetFileAndDirFromPath(targetPath)
@@ -133,14 +387,21 @@ func DownloadDependency(artDetails *config.ServerDetails, downloadPath, targetPa
 	}()
 
 	// Get the expected check-sum before downloading
-	client, httpClientDetails, err := CreateHttpClient(artDetails)
+	client, httpClientDetails, err := createDownloadClient(ctx, artDetails, options.UserAgent, options.RequestTimeout)
 	if err != nil {
 		return err
 	}
 	expectedSha1 := ""
 	remoteFileDetails, _, err := client.GetRemoteFileDetails(downloadUrl, &httpClientDetails)
+	if ctx.Err() != nil {
+		return getCanceledDownloadError(ctx, downloadUrl)
+	}
 	if err == nil {
 		expectedSha1 = remoteFileDetails.Checksum.Sha1
+		// The file is downloaded to a temporary directory, and then copied to the target directory.
+		if err = validateFreeDiskSpace(remoteFileDetails.Size, tempDirPath, localDir); err != nil {
+			return err
+		}
 	} else {
 		log.Warn(fmt.Sprintf("Failed to get remote file details.\n Got: %s", err))
 	}
@@ -152,13 +413,21 @@ func DownloadDependency(artDetails *config.ServerDetails, downloadPath, targetPa
 		LocalFileName: filename,
 		ExpectedSha1:  expectedSha1,
 	}
-	client, httpClientDetails, err = CreateHttpClient(artDetails)
+	client, httpClientDetails, err = createDownloadClient(ctx, artDetails, options.UserAgent, options.RequestTimeout)
 	if err != nil {
 		return err
 	}
-	resp, err := client.DownloadFile(downloadFileDetails, "", &httpClientDetails, shouldExplode, false)
+	resp, err := client.DownloadFileWithProgress(downloadFileDetails, "", &httpClientDetails, shouldExplode, false, getDownloadProgressMgr())
+	// Checked before the response, since a canceled download may also be reported as a timeout.
+	if ctx.Err() != nil {
+		return getCanceledDownloadError(ctx, downloadUrl)
+	}
 	if err != nil {
-		err = errorutils.CheckErrorf("received error while attempting to download '%s': %s"+downloadUrl, err.Error())
+		if isTimeoutError(err) {
+			return errorutils.CheckErrorf("timed out while attempting to download '%s'. The timeout can be configured using the %s environment variable or the download options: %s",
+				downloadUrl, coreutils.ExtractorDownloadTimeoutEnv, err.Error())
+		}
+		return errorutils.CheckErrorf("received error while attempting to download '%s': %s", downloadUrl, err.Error())
 	}
 	if err = errorutils.CheckResponseStatus(resp, http.StatusOK); err != nil {
 		return err
@@ -170,7 +439,95 @@ func DownloadDependency(artDetails *config.ServerDetails, downloadPath, targetPa
 	return biutils.CopyDir(tempDirPath, localDir, true, nil)
 }
 
+// Creates the HTTP client which downloads the dependency.
+// When downloading from a configured server, the client of an Artifactory services manager is used,
+// so the download has the same certificates, proxy and retries behavior as the other requests sent to the server.
+// The anonymous downloads from releases.jfrog.io use a client created directly.
+// Both identify themselves with the given user-agent, or if it's empty, with the jfrog-cli-core name and version.
+// Their requests time out after the given timeout, or if it's zero, after the timeout set by the environment variable.
+func createDownloadClient(ctx context.Context, artDetails *config.ServerDetails, userAgent string, requestTimeout time.Duration) (*jfroghttpclient.JfrogHttpClient, httputils.HttpClientDetails, error) {
+	if artDetails.ServerId == "" {
+		return createHttpClient(ctx, artDetails, userAgent, requestTimeout)
+	}
+	timeout, err := getRequestTimeout(requestTimeout)
+	if err != nil {
+		return nil, httputils.HttpClientDetails{}, err
+	}
+	httpClient := getDownloadHttpClient(timeout)
+	if httpClient == nil {
+		// Supports a SOCKS5 proxy set in the ALL_PROXY environment variable.
+		if httpClient, err = utils.CreateAllProxyHttpClientForServer(artDetails, timeout); err != nil {
+			return nil, httputils.HttpClientDetails{}, err
+		}
+	}
+	maxRetries, waitMs := getDownloadRetryConfig().GetRetries()
+	servicesManager, err := createDownloadServicesManager(ctx, artDetails, httpClient, maxRetries, waitMs, timeout, userAgent)
+	if err != nil {
+		return nil, httputils.HttpClientDetails{}, err
+	}
+	return servicesManager.Client(), servicesManager.GetConfig().GetServiceDetails().CreateHttpClientDetails(), nil
+}
+
+// Allows verifying in tests that the services manager is used.
+var createDownloadServicesManager = utils.CreateServiceManagerWithHttpClient
+
+func getCanceledDownloadError(ctx context.Context, downloadUrl string) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errorutils.CheckErrorf("the download of '%s' timed out: %w", downloadUrl, ctx.Err())
+	}
+	return errorutils.CheckErrorf("the download of '%s' was canceled: %w", downloadUrl, ctx.Err())
+}
+
+// Allows mocking the free disk space in tests.
+var getFreeDiskSpace = osutils.GetFreeDiskSpace
+
+// Downloading a file larger than the free disk space fails midway with an unclear write error, so we fail early with a clear one.
+// The check is skipped if the file size is unknown, or if the free space of a directory can't be determined.
+func validateFreeDiskSpace(fileSize int64, dirs ...string) error {
+	if fileSize <= 0 {
+		return nil
+	}
+	for _, dir := range dirs {
+		freeSpace, err := getFreeDiskSpace(getExistingAncestor(dir))
+		if err != nil {
+			log.Debug(fmt.Sprintf("Couldn't get the free disk space of %s, skipping the disk space check: %s", dir, err.Error()))
+			continue
+		}
+		//#nosec G115 -- The file size is positive.
+		if freeSpace < uint64(fileSize) {
+			return errorutils.CheckErrorf("insufficient disk space in %s: downloading the file requires %d bytes, but only %d bytes are available", dir, fileSize, freeSpace)
+		}
+	}
+	return nil
+}
+
+// Returns the given directory if it exists, or its closest existing ancestor. The target directory of a download may not exist yet.
+func getExistingAncestor(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
 func CreateHttpClient(artDetails *config.ServerDetails) (rtHttpClient *jfroghttpclient.JfrogHttpClient, httpClientDetails httputils.HttpClientDetails, err error) {
+	return CreateHttpClientWithUserAgent(artDetails, "")
+}
+
+// CreateHttpClientWithUserAgent creates an HTTP client, which identifies itself with the given user-agent.
+// If the given user-agent is empty, the jfrog-cli-core name and version are used.
+func CreateHttpClientWithUserAgent(artDetails *config.ServerDetails, userAgent string) (rtHttpClient *jfroghttpclient.JfrogHttpClient, httpClientDetails httputils.HttpClientDetails, err error) {
+	return createHttpClient(context.Background(), artDetails, userAgent, 0)
+}
+
+// Creates an HTTP client, whose requests are aborted when the given context is canceled, or time out after the given request timeout.
+// If the request timeout is zero, the timeout set by the environment variable is used.
+func createHttpClient(ctx context.Context, artDetails *config.ServerDetails, userAgent string, requestTimeout time.Duration) (rtHttpClient *jfroghttpclient.JfrogHttpClient, httpClientDetails httputils.HttpClientDetails, err error) {
 	auth, err := artDetails.CreateArtAuthConfig()
 	if err != nil {
 		return
@@ -180,13 +537,104 @@ func CreateHttpClient(artDetails *config.ServerDetails) (rtHttpClient *jfroghttp
 		return
 	}
 
+	timeout, err := getRequestTimeout(requestTimeout)
+	if err != nil {
+		return
+	}
+
 	httpClientDetails = auth.CreateHttpClientDetails()
-	rtHttpClient, err = jfroghttpclient.JfrogClientBuilder().
+	clientBuilder := jfroghttpclient.JfrogClientBuilder().
 		SetCertificatesPath(certsPath).
 		SetInsecureTls(artDetails.InsecureTls).
 		SetClientCertPath(auth.GetClientCertPath()).
 		SetClientCertKeyPath(auth.GetClientCertKeyPath()).
+		SetOverallRequestTimeout(timeout).
+		SetContext(ctx).
 		AppendPreRequestInterceptor(auth.RunPreRequestFunctions).
-		Build()
+		AppendPreRequestInterceptor(coreutils.CreateUserAgentInterceptor(userAgent))
+	if maxRetries, waitMs := getDownloadRetryConfig().GetRetries(); maxRetries >= 0 {
+		clientBuilder.SetRetries(maxRetries).SetRetryWaitMilliSecs(waitMs)
+	}
+	httpClient := getDownloadHttpClient(timeout)
+	if httpClient == nil {
+		// Supports a SOCKS5 proxy set in the ALL_PROXY environment variable.
+		if httpClient, err = utils.CreateAllProxyHttpClient(certsPath, artDetails.InsecureTls, auth.GetClientCertPath(), auth.GetClientCertKeyPath(), timeout); err != nil {
+			return
+		}
+	}
+	if httpClient != nil {
+		clientBuilder.SetHttpClient(httpClient)
+	}
+	rtHttpClient, err = clientBuilder.Build()
 	return
 }
+
+var (
+	downloadRetryConfig     *coreutils.RetryConfig
+	downloadRetryConfigLock sync.RWMutex
+)
+
+// SetDownloadRetryConfig sets the retries of the failed requests made while downloading the extractors,
+// independently of the retries of other requests, such as the dependencies' checksums lookups.
+// Pass nil to restore the HTTP client's default retries.
+func SetDownloadRetryConfig(retryConfig *coreutils.RetryConfig) {
+	downloadRetryConfigLock.Lock()
+	defer downloadRetryConfigLock.Unlock()
+	downloadRetryConfig = retryConfig
+}
+
+func getDownloadRetryConfig() *coreutils.RetryConfig {
+	downloadRetryConfigLock.RLock()
+	defer downloadRetryConfigLock.RUnlock()
+	return downloadRetryConfig
+}
+
+var (
+	downloadTransport     http.RoundTripper
+	downloadTransportLock sync.RWMutex
+)
+
+// SetDownloadTransport sets the HTTP transport of the requests made while downloading the extractors, for example, a mock transport in tests.
+// Pass nil to restore the default transport.
+func SetDownloadTransport(transport http.RoundTripper) {
+	downloadTransportLock.Lock()
+	defer downloadTransportLock.Unlock()
+	downloadTransport = transport
+}
+
+// Returns an HTTP client which uses the transport set by SetDownloadTransport, or nil if it isn't set.
+func getDownloadHttpClient(timeout time.Duration) *http.Client {
+	downloadTransportLock.RLock()
+	defer downloadTransportLock.RUnlock()
+	if downloadTransport == nil {
+		return nil
+	}
+	return &http.Client{Transport: downloadTransport, Timeout: timeout}
+}
+
+// Returns the timeout of each request made while downloading the dependencies, as set in the JFROG_CLI_EXTRACTOR_DOWNLOAD_TIMEOUT environment variable.
+// If the environment variable isn't set, 0 (no timeout) is returned.
+// Returns the given request timeout, or if it's zero, the timeout set by the environment variable.
+func getRequestTimeout(requestTimeout time.Duration) (time.Duration, error) {
+	if requestTimeout > 0 {
+		return requestTimeout, nil
+	}
+	return getDownloadTimeout()
+}
+
+func getDownloadTimeout() (time.Duration, error) {
+	timeoutStr := os.Getenv(coreutils.ExtractorDownloadTimeoutEnv)
+	if timeoutStr == "" {
+		return 0, nil
+	}
+	timeoutSecs, err := strconv.Atoi(timeoutStr)
+	if err != nil || timeoutSecs <= 0 {
+		return 0, errorutils.CheckErrorf("the value of the %s environment variable must be a positive number of seconds, but got: '%s'", coreutils.ExtractorDownloadTimeoutEnv, timeoutStr)
+	}
+	return time.Duration(timeoutSecs) * time.Second, nil
+}
+
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
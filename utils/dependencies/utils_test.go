@@ -1,10 +1,31 @@
 package dependencies
 
 import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	jfrogclicore "github.com/jfrog/jfrog-cli-core/v2"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/config"
 	"github.com/jfrog/jfrog-cli-core/v2/utils/coreutils"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/osutils"
+	"github.com/jfrog/jfrog-cli-core/v2/utils/tests"
+	"github.com/jfrog/jfrog-client-go/artifactory"
+	testsUtils "github.com/jfrog/jfrog-client-go/utils/tests"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -37,6 +58,96 @@ func TestGetFullRemoteRepoPath(t *testing.T) {
 	}
 }
 
+func TestGetExtractorsRemoteDetailsByType(t *testing.T) {
+	t.Setenv(coreutils.HomeDir, t.TempDir())
+	assert.NoError(t, config.SaveServersConf([]*config.ServerDetails{
+		{ServerId: "maven-server", ArtifactoryUrl: "https://maven.jfrog.io/artifactory/"},
+		{ServerId: "gradle-server", ArtifactoryUrl: "https://gradle.jfrog.io/artifactory/"},
+		{ServerId: "releases-server", ArtifactoryUrl: "https://releases-proxy.jfrog.io/artifactory/"},
+	}))
+	t.Setenv(coreutils.MavenExtractorRemoteEnv, "maven-server/maven-extractors")
+	t.Setenv(coreutils.GradleExtractorRemoteEnv, "gradle-server/gradle-extractors")
+	t.Setenv(coreutils.ReleasesRemoteEnv, "releases-server/releases")
+
+	testCases := []struct {
+		name               string
+		downloadPath       string
+		expectedServerUrl  string
+		expectedRemotePath string
+	}{
+		{
+			name:               "maven",
+			downloadPath:       "org/jfrog/buildinfo/build-info-extractor-maven3/2.41.24/build-info-extractor-maven3-2.41.24-uber.jar",
+			expectedServerUrl:  "https://maven.jfrog.io/artifactory/",
+			expectedRemotePath: "maven-extractors/artifactory/oss-release-local/org/jfrog/buildinfo/build-info-extractor-maven3/2.41.24/build-info-extractor-maven3-2.41.24-uber.jar",
+		},
+		{
+			name:               "gradle",
+			downloadPath:       "org/jfrog/buildinfo/build-info-extractor-gradle/5.2.5/build-info-extractor-gradle-5.2.5-uber.jar",
+			expectedServerUrl:  "https://gradle.jfrog.io/artifactory/",
+			expectedRemotePath: "gradle-extractors/artifactory/oss-release-local/org/jfrog/buildinfo/build-info-extractor-gradle/5.2.5/build-info-extractor-gradle-5.2.5-uber.jar",
+		},
+		{
+			name:               "other",
+			downloadPath:       "org/jfrog/buildinfo/build-info-extractor-ivy/2.41.24/build-info-extractor-ivy-2.41.24-uber.jar",
+			expectedServerUrl:  "https://releases-proxy.jfrog.io/artifactory/",
+			expectedRemotePath: "releases/artifactory/oss-release-local/org/jfrog/buildinfo/build-info-extractor-ivy/2.41.24/build-info-extractor-ivy-2.41.24-uber.jar",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server, remotePath, err := GetExtractorsRemoteDetails(testCase.downloadPath)
+			assert.NoError(t, err)
+			if assert.NotNil(t, server) {
+				assert.Equal(t, testCase.expectedServerUrl, server.ArtifactoryUrl)
+			}
+			assert.Equal(t, testCase.expectedRemotePath, remotePath)
+		})
+	}
+
+	// Without the Maven extractor environment variable, the Maven extractor falls back to the general environment variable.
+	t.Setenv(coreutils.MavenExtractorRemoteEnv, "")
+	server, remotePath, err := GetExtractorsRemoteDetails(testCases[0].downloadPath)
+	assert.NoError(t, err)
+	if assert.NotNil(t, server) {
+		assert.Equal(t, "https://releases-proxy.jfrog.io/artifactory/", server.ArtifactoryUrl)
+	}
+	assert.Equal(t, "releases/artifactory/oss-release-local/"+testCases[0].downloadPath, remotePath)
+}
+
+func TestGetExtractorsRemoteDetailsWithDeprecatedEnv(t *testing.T) {
+	t.Setenv(coreutils.HomeDir, t.TempDir())
+	assert.NoError(t, config.SaveServersConf([]*config.ServerDetails{{ServerId: "extractors-server", ArtifactoryUrl: "https://extractors.jfrog.io/artifactory/"}}))
+	t.Setenv(coreutils.DeprecatedExtractorsRemoteEnv, "extractors-server/extractors")
+	const downloadPath = "org/jfrog/buildinfo/build-info-extractor-ivy/2.41.24/build-info-extractor-ivy-2.41.24-uber.jar"
+
+	testCases := []struct {
+		name          string
+		strictEnv     string
+		expectedError string
+	}{
+		{name: "lenient by default"},
+		{name: "lenient", strictEnv: "false"},
+		{name: "strict", strictEnv: "true", expectedError: "the deprecated \"" + coreutils.DeprecatedExtractorsRemoteEnv + "\" environment variable is set"},
+		{name: "invalid", strictEnv: "yes please", expectedError: "must be a boolean"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Setenv(coreutils.FailOnDeprecatedExtractorsRemoteEnv, testCase.strictEnv)
+			server, remotePath, err := GetExtractorsRemoteDetails(downloadPath)
+			if testCase.expectedError != "" {
+				assert.ErrorContains(t, err, testCase.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			if assert.NotNil(t, server) {
+				assert.Equal(t, "https://extractors.jfrog.io/artifactory/", server.ArtifactoryUrl)
+			}
+			assert.Equal(t, "extractors/"+downloadPath, remotePath)
+		})
+	}
+}
+
 func TestCreateHttpClient(t *testing.T) {
 	serverDetails := &config.ServerDetails{
 		Url:      "https://acme.jfrog.io",
@@ -51,3 +162,741 @@ func TestCreateHttpClient(t *testing.T) {
 	assert.Equal(t, "elmar", httpClientDetails.User)
 	assert.Equal(t, "Egghead", httpClientDetails.Password)
 }
+
+func TestGetDownloadTimeout(t *testing.T) {
+	testCases := []struct {
+		envValue        string
+		expectedTimeout time.Duration
+		expectedError   bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, false},
+		{"0", 0, true},
+		{"1m", 0, true},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.envValue, func(t *testing.T) {
+			t.Setenv(coreutils.ExtractorDownloadTimeoutEnv, testCase.envValue)
+			timeout, err := getDownloadTimeout()
+			if testCase.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedTimeout, timeout)
+		})
+	}
+}
+
+func TestDownloadDependencyTimeout(t *testing.T) {
+	// A server which never responds, until the test ends.
+	testDone := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-testDone
+	}))
+	defer testServer.Close()
+	defer close(testDone)
+	t.Setenv(coreutils.ExtractorDownloadTimeoutEnv, "1")
+
+	targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+	err := DownloadDependency(serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false)
+	assert.ErrorContains(t, err, "timed out while attempting to download")
+	assert.ErrorContains(t, err, coreutils.ExtractorDownloadTimeoutEnv)
+	assert.NoFileExists(t, targetPath)
+}
+
+func TestGetRequestTimeout(t *testing.T) {
+	t.Setenv(coreutils.ExtractorDownloadTimeoutEnv, "30")
+	// The option takes precedence over the environment variable.
+	timeout, err := getRequestTimeout(5 * time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, timeout)
+	// Without the option, the environment variable is used.
+	timeout, err = getRequestTimeout(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, timeout)
+}
+
+func TestDownloadExtractorRequestTimeout(t *testing.T) {
+	// A server which never responds, until the test ends.
+	testDone := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-testDone
+	}))
+	defer testServer.Close()
+	defer close(testDone)
+	t.Setenv(coreutils.ExtractorDownloadTimeoutEnv, "")
+	SetDownloadRetryConfig(&coreutils.RetryConfig{MaxRetries: 0})
+	defer SetDownloadRetryConfig(nil)
+
+	targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+	err := downloadExtractorWithLock(context.Background(), serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath,
+		ExtractorDownloadOptions{RequestTimeout: 500 * time.Millisecond})
+	assert.ErrorContains(t, err, "timed out while attempting to download")
+	assert.NoFileExists(t, targetPath)
+}
+
+func TestDownloadExtractorOverallTimeout(t *testing.T) {
+	// A server which sends part of the extractor, and then stalls until the request is aborted.
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodGet {
+			return
+		}
+		_, err := w.Write(make([]byte, 100))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer testServer.Close()
+	// The requests themselves don't time out.
+	t.Setenv(coreutils.ExtractorDownloadTimeoutEnv, "")
+
+	targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+	start := time.Now()
+	err := downloadExtractorWithLock(context.Background(), serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath,
+		ExtractorDownloadOptions{Timeout: 500 * time.Millisecond})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "timed out")
+	assert.Less(t, time.Since(start), 10*time.Second)
+	assert.NoFileExists(t, targetPath)
+}
+
+func TestDownloadDependencyUserAgent(t *testing.T) {
+	var userAgents []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+	assert.NoError(t, DownloadDependency(serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false))
+	assert.NotEmpty(t, userAgents)
+	for _, userAgent := range userAgents {
+		assert.Equal(t, jfrogclicore.GetUserAgent(), userAgent)
+	}
+
+	// A custom user-agent
+	client, httpClientDetails, err := CreateHttpClientWithUserAgent(serverDetails, "my-agent/1.0.0")
+	assert.NoError(t, err)
+	_, _, _, err = client.SendGet(testServer.URL, true, &httpClientDetails)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-agent/1.0.0", userAgents[len(userAgents)-1])
+}
+
+func TestDownloadExtractorUserAgent(t *testing.T) {
+	var mutex sync.Mutex
+	var userAgents []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		userAgents = append(userAgents, r.Header.Get("User-Agent"))
+		mutex.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	testCases := []struct {
+		name              string
+		serverDetails     *config.ServerDetails
+		userAgent         string
+		expectedUserAgent string
+	}{
+		{name: "anonymous default", serverDetails: &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}, expectedUserAgent: jfrogclicore.GetUserAgent()},
+		{name: "anonymous custom", serverDetails: &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}, userAgent: "my-agent/1.0.0", expectedUserAgent: "my-agent/1.0.0"},
+		{name: "configured server custom", serverDetails: &config.ServerDetails{ServerId: "my-server", ArtifactoryUrl: testServer.URL + "/"}, userAgent: "my-agent/1.0.0",
+			expectedUserAgent: "my-agent/1.0.0"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			userAgents = nil
+			targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+			assert.NoError(t, downloadExtractorWithLock(context.Background(), testCase.serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath,
+				ExtractorDownloadOptions{UserAgent: testCase.userAgent}))
+			assert.NotEmpty(t, userAgents)
+			for _, userAgent := range userAgents {
+				assert.Equal(t, testCase.expectedUserAgent, userAgent)
+			}
+		})
+	}
+}
+
+func TestDownloadDependencyRetries(t *testing.T) {
+	// A server which fails the first download attempt of each test case.
+	var downloadAttempts atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && downloadAttempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+	defer SetDownloadRetryConfig(nil)
+
+	testCases := []struct {
+		name             string
+		retryConfig      *coreutils.RetryConfig
+		expectedError    bool
+		expectedAttempts int32
+	}{
+		{name: "default", expectedError: true, expectedAttempts: 1},
+		{name: "negative retries", retryConfig: &coreutils.RetryConfig{MaxRetries: -1, WaitMs: 10}, expectedError: true, expectedAttempts: 1},
+		{name: "one retry", retryConfig: &coreutils.RetryConfig{MaxRetries: 1, WaitMs: 10}, expectedAttempts: 2},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			downloadAttempts.Store(0)
+			SetDownloadRetryConfig(testCase.retryConfig)
+			targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+			err := DownloadDependency(serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false)
+			if testCase.expectedError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, testCase.expectedAttempts, downloadAttempts.Load())
+		})
+	}
+}
+
+func TestDownloadDependencyInsufficientDiskSpace(t *testing.T) {
+	var downloadsCount atomic.Int32
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			downloadsCount.Add(1)
+		}
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(make([]byte, 1000))
+		}
+	}))
+	defer testServer.Close()
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+	defer func() {
+		getFreeDiskSpace = osutils.GetFreeDiskSpace
+	}()
+
+	// Low disk space.
+	getFreeDiskSpace = func(string) (uint64, error) { return 999, nil }
+	targetPath := filepath.Join(t.TempDir(), "extractors", "build-info-extractor-maven3-2.0.0-uber.jar")
+	err := DownloadDependency(serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false)
+	assert.ErrorContains(t, err, "insufficient disk space")
+	assert.Zero(t, downloadsCount.Load())
+	assert.NoFileExists(t, targetPath)
+
+	// Enough disk space.
+	getFreeDiskSpace = func(string) (uint64, error) { return 1000, nil }
+	assert.NoError(t, DownloadDependency(serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false))
+	assert.FileExists(t, targetPath)
+}
+
+func TestValidateFreeDiskSpace(t *testing.T) {
+	defer func() {
+		getFreeDiskSpace = osutils.GetFreeDiskSpace
+	}()
+	getFreeDiskSpace = func(string) (uint64, error) { return 100, nil }
+	// Unknown file size.
+	assert.NoError(t, validateFreeDiskSpace(-1, t.TempDir()))
+	assert.NoError(t, validateFreeDiskSpace(100, t.TempDir()))
+	assert.ErrorContains(t, validateFreeDiskSpace(101, t.TempDir()), "insufficient disk space")
+	// The free disk space can't be determined.
+	getFreeDiskSpace = func(string) (uint64, error) { return 0, errors.New("unsupported") }
+	assert.NoError(t, validateFreeDiskSpace(101, t.TempDir()))
+}
+
+func TestDownloadExtractorConcurrently(t *testing.T) {
+	var downloadsCount atomic.Int32
+	extractorContent := []byte("extractor-jar-content")
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			downloadsCount.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write(extractorContent)
+		assert.NoError(t, err)
+	}))
+	defer testServer.Close()
+
+	tmpDir, createTempDirCallback := tests.CreateTempDirWithCallbackAndAssert(t)
+	defer createTempDirCallback()
+	targetPath := filepath.Join(tmpDir, "build-info-extractor-maven3-2.0.0-uber.jar")
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, downloadExtractorWithLock(context.Background(), serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, ExtractorDownloadOptions{}))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), downloadsCount.Load())
+	content, err := os.ReadFile(targetPath)
+	assert.NoError(t, err)
+	assert.Equal(t, extractorContent, content)
+}
+
+func TestDownloadExtractorWithOptions(t *testing.T) {
+	var downloadsCount atomic.Int32
+	extractorContent := []byte("new-extractor-jar-content")
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			downloadsCount.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write(extractorContent)
+		assert.NoError(t, err)
+	}))
+	defer testServer.Close()
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+
+	testCases := []struct {
+		name              string
+		cachedAge         time.Duration
+		options           ExtractorDownloadOptions
+		expectedDownloads int32
+	}{
+		{name: "existence only", cachedAge: 48 * time.Hour},
+		{name: "within the max age", cachedAge: time.Minute, options: ExtractorDownloadOptions{MaxAge: time.Hour}},
+		{name: "max age expired", cachedAge: 2 * time.Hour, options: ExtractorDownloadOptions{MaxAge: time.Hour}, expectedDownloads: 1},
+		{name: "same version", options: ExtractorDownloadOptions{Version: "2.0.0"}},
+		{name: "version mismatch", options: ExtractorDownloadOptions{Version: "2.1.0"}, expectedDownloads: 1},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			downloadsCount.Store(0)
+			targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+			assert.NoError(t, os.WriteFile(targetPath, []byte("cached-extractor-jar-content"), 0644))
+			cachedTime := time.Now().Add(-testCase.cachedAge)
+			assert.NoError(t, os.Chtimes(targetPath, cachedTime, cachedTime))
+
+			assert.NoError(t, downloadExtractorWithLock(context.Background(), serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, testCase.options))
+			assert.Equal(t, testCase.expectedDownloads, downloadsCount.Load())
+			content, err := os.ReadFile(targetPath)
+			assert.NoError(t, err)
+			if testCase.expectedDownloads > 0 {
+				assert.Equal(t, extractorContent, content)
+			} else {
+				assert.Equal(t, []byte("cached-extractor-jar-content"), content)
+			}
+
+			// The downloaded extractor is up-to-date.
+			assert.NoError(t, downloadExtractorWithLock(context.Background(), serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, testCase.options))
+			assert.Equal(t, testCase.expectedDownloads, downloadsCount.Load())
+		})
+	}
+}
+
+func TestGetExtractorLockWait(t *testing.T) {
+	defer SetDownloadRetryConfig(nil)
+	testCases := []struct {
+		name         string
+		timeout      string
+		retryConfig  *coreutils.RetryConfig
+		expectedWait time.Duration
+	}{
+		{name: "default", expectedWait: defaultExtractorLockWait},
+		{name: "short timeout", timeout: "30", expectedWait: defaultExtractorLockWait},
+		{name: "long timeout", timeout: "300", expectedWait: 10 * time.Minute},
+		{name: "long timeout with retries", timeout: "300", retryConfig: &coreutils.RetryConfig{MaxRetries: 2}, expectedWait: 30 * time.Minute},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Setenv(coreutils.ExtractorDownloadTimeoutEnv, testCase.timeout)
+			SetDownloadRetryConfig(testCase.retryConfig)
+			lockWait, err := getExtractorLockWait(0)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedWait, lockWait)
+		})
+	}
+}
+
+func TestDownloadExtractorLockTimeout(t *testing.T) {
+	previousGetExtractorLockWait := getExtractorLockWait
+	defer func() {
+		getExtractorLockWait = previousGetExtractorLockWait
+	}()
+	getExtractorLockWait = func(time.Duration) (time.Duration, error) {
+		return 300 * time.Millisecond, nil
+	}
+	// No request is expected, since the lock isn't acquired.
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: "http://127.0.0.1:1/"}
+
+	testCases := []struct {
+		name              string
+		extractorExists   bool
+		expectedErrSubstr string
+	}{
+		{name: "extractor downloaded by the lock holder", extractorExists: true},
+		{name: "extractor missing", expectedErrSubstr: "lock hasn't been acquired"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+			// A lock held by a running process (this one), which was requested earlier and is therefore preferred.
+			locksDir := filepath.Join(filepath.Dir(targetPath), extractorLocksDirName)
+			assert.NoError(t, os.MkdirAll(locksDir, 0755))
+			heldLockTime := time.Now().Add(-time.Minute).UnixNano()
+			assert.NoError(t, os.WriteFile(filepath.Join(locksDir, fmt.Sprintf("jfrog-cli.conf.lck.%d.%d", os.Getpid(), heldLockTime)), nil, 0644))
+			if testCase.extractorExists {
+				assert.NoError(t, os.WriteFile(targetPath, []byte("extractor-jar-content"), 0644))
+			}
+
+			err := downloadExtractorWithLock(context.Background(), serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, ExtractorDownloadOptions{})
+			if testCase.expectedErrSubstr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, testCase.expectedErrSubstr)
+			}
+		})
+	}
+}
+
+func TestDownloadExtractorCanceled(t *testing.T) {
+	// A server which sends part of the extractor, and then stalls until the request is aborted.
+	partSent := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1000")
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodGet {
+			return
+		}
+		_, err := w.Write(make([]byte, 100))
+		assert.NoError(t, err)
+		w.(http.Flusher).Flush()
+		close(partSent)
+		<-r.Context().Done()
+	}))
+	defer testServer.Close()
+	serverDetails := &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-partSent
+		cancel()
+	}()
+	localDir := t.TempDir()
+	targetPath := filepath.Join(localDir, "build-info-extractor-maven3-2.0.0-uber.jar")
+	err := downloadExtractorWithLock(ctx, serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, ExtractorDownloadOptions{Version: "2.0.0"})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "was canceled")
+
+	// Nothing but the lock directory is left in the local directory.
+	entries, err := os.ReadDir(localDir)
+	assert.NoError(t, err)
+	var entryNames []string
+	for _, entry := range entries {
+		entryNames = append(entryNames, entry.Name())
+	}
+	assert.Equal(t, []string{extractorLocksDirName}, entryNames)
+
+	// An already canceled context fails without downloading.
+	err = DownloadDependencyWithContext(ctx, serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoFileExists(t, targetPath)
+}
+
+func TestDownloadDependencyWithServicesManager(t *testing.T) {
+	var authHeaders []string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, err := w.Write([]byte("extractor"))
+			assert.NoError(t, err)
+		}
+	}))
+	defer testServer.Close()
+
+	previousCreateDownloadServicesManager := createDownloadServicesManager
+	defer func() {
+		createDownloadServicesManager = previousCreateDownloadServicesManager
+	}()
+	var servicesManagersCreated int
+	createDownloadServicesManager = func(ctx context.Context, serverDetails *config.ServerDetails, httpClient *http.Client, httpRetries, httpRetryWaitMilliSecs int, timeout time.Duration, userAgent string) (artifactory.ArtifactoryServicesManager, error) {
+		servicesManagersCreated++
+		return previousCreateDownloadServicesManager(ctx, serverDetails, httpClient, httpRetries, httpRetryWaitMilliSecs, timeout, userAgent)
+	}
+
+	testCases := []struct {
+		name                            string
+		serverDetails                   *config.ServerDetails
+		expectedServicesManagersCreated int
+		expectedAuthHeader              string
+	}{
+		{name: "anonymous", serverDetails: &config.ServerDetails{ArtifactoryUrl: testServer.URL + "/"}},
+		{
+			name:                            "configured server",
+			serverDetails:                   &config.ServerDetails{ServerId: "my-server", ArtifactoryUrl: testServer.URL + "/", AccessToken: "my-token"},
+			expectedServicesManagersCreated: 2,
+			expectedAuthHeader:              "Bearer my-token",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			servicesManagersCreated = 0
+			authHeaders = nil
+			targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+			assert.NoError(t, DownloadDependency(testCase.serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false))
+			assert.Equal(t, testCase.expectedServicesManagersCreated, servicesManagersCreated)
+			assert.NotEmpty(t, authHeaders)
+			for _, authHeader := range authHeaders {
+				assert.Equal(t, testCase.expectedAuthHeader, authHeader)
+			}
+			content, err := os.ReadFile(targetPath)
+			assert.NoError(t, err)
+			assert.Equal(t, "extractor", string(content))
+		})
+	}
+}
+
+// A mock HTTP transport, which records the requests, and responds to them successfully, with the given body to GET requests.
+type mockTransport struct {
+	mutex    sync.Mutex
+	requests []*http.Request
+	body     string
+}
+
+func (mt *mockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	mt.mutex.Lock()
+	defer mt.mutex.Unlock()
+	mt.requests = append(mt.requests, req)
+	body := ""
+	if req.Method == http.MethodGet {
+		body = mt.body
+	}
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)), Request: req}, nil
+}
+
+func TestDownloadDependencyWithMockTransport(t *testing.T) {
+	transport := &mockTransport{body: "extractor"}
+	SetDownloadTransport(transport)
+	defer SetDownloadTransport(nil)
+
+	testCases := []struct {
+		name               string
+		serverDetails      *config.ServerDetails
+		expectedAuthHeader string
+	}{
+		{name: "anonymous", serverDetails: &config.ServerDetails{ArtifactoryUrl: "https://releases.example.com/"}},
+		{
+			name:               "configured server",
+			serverDetails:      &config.ServerDetails{ServerId: "my-server", ArtifactoryUrl: "https://releases.example.com/", AccessToken: "my-token"},
+			expectedAuthHeader: "Bearer my-token",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transport.requests = nil
+			targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+			assert.NoError(t, DownloadDependency(testCase.serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false))
+			content, err := os.ReadFile(targetPath)
+			assert.NoError(t, err)
+			assert.Equal(t, "extractor", string(content))
+
+			// The remote file details are requested before the download.
+			var methods []string
+			for _, request := range transport.requests {
+				methods = append(methods, request.Method)
+				assert.Equal(t, "https://releases.example.com/oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", request.URL.String())
+				assert.Equal(t, testCase.expectedAuthHeader, request.Header.Get("Authorization"))
+				assert.Equal(t, jfrogclicore.GetUserAgent(), request.Header.Get("User-Agent"))
+			}
+			assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods)
+		})
+	}
+}
+
+func TestDownloadDependencyThroughSocksProxy(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			_, err := w.Write([]byte("extractor"))
+			assert.NoError(t, err)
+		}
+	}))
+	defer testServer.Close()
+	proxyAddress, getRequestedDestinations := startStubSocksProxy(t, testServer.Listener.Addr().String())
+	for _, env := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy", "all_proxy"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("ALL_PROXY", "socks5://"+proxyAddress)
+
+	testCases := []struct {
+		name          string
+		serverDetails *config.ServerDetails
+	}{
+		// The proxy is skipped for loopback addresses, so the server is addressed by a host name, which only the proxy resolves.
+		{name: "anonymous", serverDetails: &config.ServerDetails{ArtifactoryUrl: "http://artifactory.test/"}},
+		{name: "configured server", serverDetails: &config.ServerDetails{ServerId: "my-server", ArtifactoryUrl: "http://artifactory.test/"}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			targetPath := filepath.Join(t.TempDir(), "build-info-extractor-maven3-2.0.0-uber.jar")
+			assert.NoError(t, DownloadDependency(testCase.serverDetails, "oss-release-local/build-info-extractor-maven3-2.0.0-uber.jar", targetPath, false))
+			content, err := os.ReadFile(targetPath)
+			assert.NoError(t, err)
+			assert.Equal(t, "extractor", string(content))
+		})
+	}
+	assert.Contains(t, getRequestedDestinations(), "artifactory.test:80")
+}
+
+// Starts a SOCKS5 proxy, which supports unauthenticated CONNECT requests only, and connects all of them to the given target address.
+// Returns the address of the proxy, and a function which returns the destinations requested from it.
+func startStubSocksProxy(t *testing.T, targetAddress string) (proxyAddress string, getRequestedDestinations func() []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() {
+		assert.NoError(t, listener.Close())
+	})
+	var mutex sync.Mutex
+	var requestedDestinations []string
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				destination, err := handleSocksConnection(conn, targetAddress)
+				if err != nil {
+					t.Logf("SOCKS proxy error: %s", err.Error())
+					return
+				}
+				mutex.Lock()
+				defer mutex.Unlock()
+				requestedDestinations = append(requestedDestinations, destination)
+			}()
+		}
+	}()
+	return listener.Addr().String(), func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return slices.Clone(requestedDestinations)
+	}
+}
+
+// Handles a single SOCKS5 connection, and returns the requested destination once the target connection is established.
+// The data is relayed between the connections in the background.
+func handleSocksConnection(conn net.Conn, targetAddress string) (destination string, err error) {
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, conn.Close())
+		}
+	}()
+	// Greeting: version, number of authentication methods, methods.
+	header := make([]byte, 2)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return
+	}
+	if _, err = io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return
+	}
+	// No authentication.
+	if _, err = conn.Write([]byte{5, 0}); err != nil {
+		return
+	}
+	// Request: version, command, reserved, address type, address, port.
+	request := make([]byte, 4)
+	if _, err = io.ReadFull(conn, request); err != nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, net.IPv4len)
+		if _, err = io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case 3:
+		length := make([]byte, 1)
+		if _, err = io.ReadFull(conn, length); err != nil {
+			return
+		}
+		domain := make([]byte, length[0])
+		if _, err = io.ReadFull(conn, domain); err != nil {
+			return
+		}
+		host = string(domain)
+	default:
+		return "", fmt.Errorf("unsupported address type %d", request[3])
+	}
+	port := make([]byte, 2)
+	if _, err = io.ReadFull(conn, port); err != nil {
+		return
+	}
+	target, err := net.Dial("tcp", targetAddress)
+	if err != nil {
+		return
+	}
+	// Succeeded, bound to 0.0.0.0:0.
+	if _, err = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0}); err != nil {
+		return "", errors.Join(err, target.Close())
+	}
+	go func() {
+		_, _ = io.Copy(target, conn)
+		_ = target.Close()
+	}()
+	go func() {
+		_, _ = io.Copy(conn, target)
+		_ = conn.Close()
+	}()
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+func TestListCachedExtractors(t *testing.T) {
+	dependenciesDir := t.TempDir()
+	mavenJar := filepath.Join(dependenciesDir, "maven", "2.41.24", "build-info-extractor-maven3-2.41.24-uber.jar")
+	gradleJar := filepath.Join(dependenciesDir, "gradle", "5.2.5", "build-info-extractor-gradle-5.2.5-uber.jar")
+	unversionedJar := filepath.Join(dependenciesDir, "gradle", "extractor.jar")
+	for _, jar := range []string{mavenJar, gradleJar, unversionedJar} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(jar), 0755))
+		assert.NoError(t, os.WriteFile(jar, []byte(filepath.Base(jar)), 0644))
+	}
+	// Files which aren't jars, and the lock files of the downloads, should be ignored.
+	assert.NoError(t, os.WriteFile(filepath.Join(dependenciesDir, "maven", "2.41.24", ChecksumFileName), []byte("checksum"), 0644))
+	assert.NoError(t, os.MkdirAll(filepath.Join(dependenciesDir, "maven", extractorLocksDirName), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dependenciesDir, "maven", extractorLocksDirName, "lock.jar"), []byte("lock"), 0644))
+
+	extractors, err := ListCachedExtractors(dependenciesDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []ExtractorInfo{
+		{Name: "build-info-extractor-gradle-5.2.5-uber.jar", Path: gradleJar, Size: int64(len("build-info-extractor-gradle-5.2.5-uber.jar")), Version: "5.2.5"},
+		{Name: "extractor.jar", Path: unversionedJar, Size: int64(len("extractor.jar"))},
+		{Name: "build-info-extractor-maven3-2.41.24-uber.jar", Path: mavenJar, Size: int64(len("build-info-extractor-maven3-2.41.24-uber.jar")), Version: "2.41.24"},
+	}, extractors)
+}
+
+func TestListCachedExtractorsRelativeDir(t *testing.T) {
+	dependenciesDir := t.TempDir()
+	jar := filepath.Join(dependenciesDir, "maven", "2.41.24", "build-info-extractor-maven3-2.41.24-uber.jar")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(jar), 0755))
+	assert.NoError(t, os.WriteFile(jar, []byte("jar"), 0644))
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	chdirCallback := testsUtils.ChangeDirWithCallback(t, wd, dependenciesDir)
+	defer chdirCallback()
+
+	// The paths are absolute, even if the directory is relative.
+	extractors, err := ListCachedExtractors("maven")
+	assert.NoError(t, err)
+	if assert.Len(t, extractors, 1) {
+		assert.Equal(t, jar, extractors[0].Path)
+	}
+}
@@ -1,6 +1,7 @@
 package lock
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -66,15 +67,25 @@ func (lock *Lock) createFile(folderName string) error {
 	return nil
 }
 
+// The number of attempts to acquire a lock, 100 milliseconds apart, before CreateLock gives up.
+const defaultLockAttempts = 1200
+
 // Try to acquire a lock
 func (lock *Lock) lock() error {
+	return lock.lockWithContext(context.Background(), defaultLockAttempts)
+}
+
+// Try to acquire a lock, until the given context is done. If maxAttempts is positive, it also limits the number of attempts.
+func (lock *Lock) lockWithContext(ctx context.Context, maxAttempts int) error {
 	filesList, err := lock.getListOfFiles()
 	if err != nil {
 		return err
 	}
 	i := 0
-	// Trying 1200 times to acquire a lock
-	for i <= 1200 {
+	for maxAttempts <= 0 || i <= maxAttempts {
+		if ctx.Err() != nil {
+			return fmt.Errorf("lock hasn't been acquired: %w", ctx.Err())
+		}
 		// If only one file, means that the process that is running is the one that created the file.
 		// We can continue
 		if len(filesList) == 1 {
@@ -210,6 +221,17 @@ func (lock *Lock) Unlock() error {
 }
 
 func CreateLock(lockDirPath string) (unlock func() error, err error) {
+	return createLock(context.Background(), lockDirPath, defaultLockAttempts)
+}
+
+// CreateLockWithContext is the same as CreateLock, but waits for the lock until the given context is done, rather than for a fixed time.
+// This suits locks which may be held longer than CreateLock waits, such as during a download with a configurable timeout.
+// As with CreateLock, the returned unlock function must be called even if an error is returned.
+func CreateLockWithContext(ctx context.Context, lockDirPath string) (unlock func() error, err error) {
+	return createLock(ctx, lockDirPath, 0)
+}
+
+func createLock(ctx context.Context, lockDirPath string, maxAttempts int) (unlock func() error, err error) {
 	log.Debug("Creating lock in:", lockDirPath)
 	lockFile := new(Lock)
 	unlock = func() error { return lockFile.Unlock() }
@@ -219,7 +241,7 @@ func CreateLock(lockDirPath string) (unlock func() error, err error) {
 	}
 
 	// Trying to acquire a lock for the running process.
-	err = lockFile.lock()
+	err = lockFile.lockWithContext(ctx, maxAttempts)
 	if err != nil {
 		err = errorutils.CheckError(err)
 	}
@@ -1,6 +1,7 @@
 package lock
 
 import (
+	"context"
 	"math"
 	"os"
 	"path/filepath"
@@ -80,6 +81,25 @@ func TestLockBiggerPid(t *testing.T) {
 	assert.Empty(t, files)
 }
 
+func TestCreateLockWithContext(t *testing.T) {
+	// A lock held by a running process (this one).
+	heldLock := getLock(os.Getpid(), t)
+	defer func() {
+		assert.NoError(t, heldLock.Unlock())
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	unlock, err := CreateLockWithContext(ctx, testLockDirPath)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NoError(t, unlock())
+
+	// Once the lock is released, it's acquired.
+	assert.NoError(t, heldLock.Unlock())
+	unlock, err = CreateLockWithContext(context.Background(), testLockDirPath)
+	assert.NoError(t, err)
+	assert.NoError(t, unlock())
+}
+
 func TestUnlock(t *testing.T) {
 	lock := new(Lock)
 	assert.NotZero(t, testLockDirPath, "An error occurred while initializing testLockDirPath")
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package osutils
+
+import (
+	"runtime"
+
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// GetFreeDiskSpace isn't supported on this operating system, and always returns an error.
+func GetFreeDiskSpace(string) (uint64, error) {
+	return 0, errorutils.CheckErrorf("getting the free disk space is not supported on %s", runtime.GOOS)
+}
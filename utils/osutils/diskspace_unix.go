@@ -0,0 +1,20 @@
+//go:build linux || darwin || freebsd
+
+package osutils
+
+import (
+	"syscall"
+
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+)
+
+// GetFreeDiskSpace returns the number of bytes available to the current user in the file system of the given path.
+func GetFreeDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, errorutils.CheckError(err)
+	}
+	// The types of the fields differ between the operating systems.
+	//#nosec G115 -- The available blocks count and the block size are never negative.
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
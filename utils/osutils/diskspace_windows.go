@@ -0,0 +1,19 @@
+package osutils
+
+import (
+	"github.com/jfrog/jfrog-client-go/utils/errorutils"
+	"golang.org/x/sys/windows"
+)
+
+// GetFreeDiskSpace returns the number of bytes available to the current user in the file system of the given path.
+func GetFreeDiskSpace(path string) (uint64, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, errorutils.CheckError(err)
+	}
+	var freeBytesAvailable uint64
+	if err = windows.GetDiskFreeSpaceEx(pathPtr, &freeBytesAvailable, nil, nil); err != nil {
+		return 0, errorutils.CheckError(err)
+	}
+	return freeBytesAvailable, nil
+}